@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outcome of one scheduled probe run
+type Result struct {
+	Name   string
+	Probe  string
+	Server string // Names of servers, comma separated
+	Begin  time.Time
+	Time   float64         // duration in ms
+	Perf   json.RawMessage // upload.Perf or download.Report
+}
+
+// Last results per schedule, in memory and appended
+// to Dir/<name>.jsonl when Dir is set.
+type History struct {
+	Dir  string
+	Size int // Results kept per schedule
+
+	mu      sync.Mutex
+	results map[string][]Result
+	lines   map[string]int // Results in the file per schedule
+}
+
+func newHistory(dir string, size int) *History {
+	return &History{Dir: dir, Size: size, results: make(map[string][]Result), lines: make(map[string]int)}
+}
+
+func (h *History) path(name string) string {
+	return filepath.Join(h.Dir, name+".jsonl")
+}
+
+// Read the results of name from disk and compact the file
+// to the last Size results.
+func (h *History) Load(name string) error {
+	if h.Dir == "" {
+		return nil
+	}
+	f, e := os.Open(h.path(name))
+	if os.IsNotExist(e) {
+		return nil
+	}
+	if e != nil {
+		return e
+	}
+	var results []Result
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var res Result
+		if e := json.Unmarshal(scanner.Bytes(), &res); e != nil {
+			f.Close()
+			return e
+		}
+		results = append(results, res)
+	}
+	f.Close()
+	if e := scanner.Err(); e != nil {
+		return e
+	}
+
+	compact := len(results) > h.Size
+	if compact {
+		results = results[len(results)-h.Size:]
+	}
+	h.mu.Lock()
+	h.results[name] = results
+	h.lines[name] = len(results)
+	h.mu.Unlock()
+	if !compact {
+		return nil
+	}
+	return h.rewrite(name, results)
+}
+
+// Replace the file of name with results
+func (h *History) rewrite(name string, results []Result) error {
+	tmp := h.path(name) + ".tmp"
+	out, e := os.Create(tmp)
+	if e != nil {
+		return e
+	}
+	enc := json.NewEncoder(out)
+	for _, res := range results {
+		if e := enc.Encode(res); e != nil {
+			out.Close()
+			return e
+		}
+	}
+	if e := out.Close(); e != nil {
+		return e
+	}
+	return os.Rename(tmp, h.path(name))
+}
+
+// Keep res and append it to the file, which is compacted to
+// the last Size results once it holds half as many more.
+func (h *History) Add(res Result) error {
+	h.mu.Lock()
+	results := append(h.results[res.Name], res)
+	if len(results) > h.Size {
+		results = results[len(results)-h.Size:]
+	}
+	h.results[res.Name] = results
+	h.lines[res.Name]++
+	compact := h.lines[res.Name] > h.Size+h.Size/2
+	if compact {
+		h.lines[res.Name] = len(results)
+	}
+	h.mu.Unlock()
+
+	if h.Dir == "" {
+		return nil
+	}
+	if compact {
+		return h.rewrite(res.Name, results)
+	}
+	f, e := os.OpenFile(h.path(res.Name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if e != nil {
+		return e
+	}
+	if e := json.NewEncoder(f).Encode(res); e != nil {
+		f.Close()
+		return e
+	}
+	return f.Close()
+}
+
+// Most recent result of name
+func (h *History) Last(name string) (Result, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	results := h.results[name]
+	if len(results) == 0 {
+		return Result{}, false
+	}
+	return results[len(results)-1], true
+}
+
+// Results of ?name= or of all schedules, oldest first
+func (h *History) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	out := make(map[string][]Result)
+	for name, results := range h.results {
+		if n := r.URL.Query().Get("name"); n != "" && n != name {
+			continue
+		}
+		out[name] = results
+	}
+	h.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
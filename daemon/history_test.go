@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Time of every result in the file of name, oldest first
+func fileTimes(t *testing.T, h *History, name string) []float64 {
+	f, e := os.Open(h.path(name))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer f.Close()
+	out := []float64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var res Result
+		if e := json.Unmarshal(scanner.Bytes(), &res); e != nil {
+			t.Fatal(e)
+		}
+		out = append(out, res.Time)
+	}
+	if e := scanner.Err(); e != nil {
+		t.Fatal(e)
+	}
+	return out
+}
+
+func memTimes(h *History, name string) []float64 {
+	out := []float64{}
+	for _, res := range h.results[name] {
+		out = append(out, res.Time)
+	}
+	return out
+}
+
+func TestHistoryCompact(t *testing.T) {
+	dir, e := ioutil.TempDir("", "history")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	h := newHistory(dir, 4)
+	begin := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	add := func(i int) {
+		res := Result{Name: "test", Probe: "download", Begin: begin.Add(time.Duration(i) * time.Minute), Time: float64(i)}
+		if e := h.Add(res); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	// Up to Size*1.5 lines the file is only appended
+	for i := 1; i <= 6; i++ {
+		add(i)
+	}
+	if got := fileTimes(t, h, "test"); !reflect.DeepEqual(got, []float64{1, 2, 3, 4, 5, 6}) {
+		t.Fatalf("File before compaction got=%v", got)
+	}
+	if got := memTimes(h, "test"); !reflect.DeepEqual(got, []float64{3, 4, 5, 6}) {
+		t.Fatalf("Memory expect last 4 but got=%v", got)
+	}
+
+	// One more rewrites the file to the last Size results
+	add(7)
+	if got := fileTimes(t, h, "test"); !reflect.DeepEqual(got, []float64{4, 5, 6, 7}) {
+		t.Fatalf("File after compaction got=%v", got)
+	}
+	add(8)
+	if got := fileTimes(t, h, "test"); !reflect.DeepEqual(got, []float64{4, 5, 6, 7, 8}) {
+		t.Fatalf("File after append got=%v", got)
+	}
+
+	// Reload the compacted file, Load trims it to Size again
+	h = newHistory(dir, 4)
+	if e := h.Load("test"); e != nil {
+		t.Fatal(e)
+	}
+	if got := memTimes(h, "test"); !reflect.DeepEqual(got, []float64{5, 6, 7, 8}) {
+		t.Fatalf("Loaded got=%v", got)
+	}
+	if got := fileTimes(t, h, "test"); !reflect.DeepEqual(got, []float64{5, 6, 7, 8}) {
+		t.Fatalf("File after Load got=%v", got)
+	}
+	last, ok := h.Last("test")
+	if !ok || !last.Begin.Equal(begin.Add(8*time.Minute)) {
+		t.Fatalf("Last expect result 8 but got=%+v", last)
+	}
+
+	// Compaction counts from the reloaded lines
+	for i := 9; i <= 10; i++ {
+		add(i)
+	}
+	if got := fileTimes(t, h, "test"); len(got) != 6 {
+		t.Fatalf("File expect 6 lines but got=%v", got)
+	}
+	add(11)
+	if got := fileTimes(t, h, "test"); !reflect.DeepEqual(got, []float64{8, 9, 10, 11}) {
+		t.Fatalf("File after second compaction got=%v", got)
+	}
+}
@@ -0,0 +1,99 @@
+// Long running service scheduling upload and download
+// probes, results are kept in History and exported on /metrics.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sla/lib/metrics"
+	"sync"
+	"syscall"
+)
+
+type Config struct {
+	Listen     string // ip:port for /metrics and /history
+	HistoryDir string // Empty to only keep history in memory
+	History    int    // Results kept per schedule
+	Schedules  []*Schedule
+}
+
+var registry = metrics.NewRegistry()
+var sla = metrics.NewSLA(registry)
+
+func loadConfig(file string) (Config, error) {
+	var c Config
+	r, e := os.Open(file)
+	if e != nil {
+		return c, e
+	}
+	e = json.NewDecoder(r).Decode(&c)
+	return c, e
+}
+
+func main() {
+	var verbose bool
+	var configPath string
+	flag.BoolVar(&verbose, "v", false, "Verbosity")
+	flag.StringVar(&configPath, "c", "./config.json", "/Path/to/config.json")
+	flag.Parse()
+
+	c, e := loadConfig(configPath)
+	if e != nil {
+		log.Fatal(e)
+	}
+	if c.History <= 0 {
+		c.History = 288
+	}
+	h := newHistory(c.HistoryDir, c.History)
+	names := make(map[string]bool)
+	for _, s := range c.Schedules {
+		if e := s.prepare(verbose); e != nil {
+			log.Fatal(e)
+		}
+		if names[s.Name] {
+			log.Fatalf("Duplicate schedule: %s", s.Name)
+		}
+		names[s.Name] = true
+		if e := h.Load(s.Name); e != nil {
+			log.Fatalf("%s: history: %s", s.Name, e)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		log.Printf("Stopping on %s", <-sig)
+		cancel()
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+	mux.Handle("/history", h)
+	srv := &http.Server{Addr: c.Listen, Handler: mux}
+	go func() {
+		if e := srv.ListenAndServe(); e != nil && e != http.ErrServerClosed {
+			log.Fatal(e)
+		}
+	}()
+
+	wg := new(sync.WaitGroup)
+	for _, s := range c.Schedules {
+		wg.Add(1)
+		go func(s *Schedule) {
+			defer wg.Done()
+			s.Loop(ctx, h)
+		}(s)
+	}
+	log.Printf("Listening on %s with %d schedules", c.Listen, len(c.Schedules))
+
+	<-ctx.Done()
+	srv.Close()
+	wg.Wait()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sla/lib/download"
+	"sla/lib/duration"
+	"sla/lib/upload"
+	"strings"
+	"time"
+)
+
+const PROBE_UPLOAD = "upload"
+const PROBE_DOWNLOAD = "download"
+
+// One probe run every Interval
+type Schedule struct {
+	Name     string // Unique, used for history
+	Probe    string // PROBE_UPLOAD or PROBE_DOWNLOAD
+	Interval duration.Duration
+	Sweep    bool            // download.Sweep instead of download.Run
+	Overview bool            // download.Overview instead of download.Run
+	Config   json.RawMessage // upload.Config or download.Config
+	Options  json.RawMessage // upload.Options or download.Options
+
+	upload       upload.Config
+	uploadOpts   upload.Options
+	download     download.Config
+	downloadOpts download.Options
+	server       string
+}
+
+// Decode Config/Options for s.Probe
+func (s *Schedule) prepare(verbose bool) error {
+	if s.Name == "" {
+		s.Name = s.Probe
+	}
+	if s.Interval.Duration <= 0 {
+		return fmt.Errorf("%s: Interval missing", s.Name)
+	}
+	var conf, opts interface{}
+	switch s.Probe {
+	case PROBE_UPLOAD:
+		conf, opts = &s.upload, &s.uploadOpts
+	case PROBE_DOWNLOAD:
+		conf, opts = &s.download, &s.downloadOpts
+	default:
+		return fmt.Errorf("%s: Invalid probe: %s", s.Name, s.Probe)
+	}
+	if e := json.Unmarshal(s.Config, conf); e != nil {
+		return fmt.Errorf("%s: Config: %s", s.Name, e)
+	}
+	if len(s.Options) > 0 {
+		if e := json.Unmarshal(s.Options, opts); e != nil {
+			return fmt.Errorf("%s: Options: %s", s.Name, e)
+		}
+	}
+	s.uploadOpts.Verbose = verbose
+	s.downloadOpts.Verbose = verbose
+	if s.Probe == PROBE_UPLOAD {
+		s.server = upload.Servers(s.upload)[0].Name
+	} else {
+		var names []string
+		for _, server := range download.Servers(s.download) {
+			names = append(names, server.Name)
+		}
+		s.server = strings.Join(names, ",")
+	}
+	return nil
+}
+
+// Run the probe once, upload renames the NZB into place so
+// downloads can run at the same time.
+func (s *Schedule) Run(ctx context.Context) Result {
+	begin := time.Now()
+	var perf interface{}
+	switch s.Probe {
+	case PROBE_UPLOAD:
+		p, e := upload.Run(ctx, s.upload, s.uploadOpts)
+		if e != nil {
+			p = upload.Failed(e)
+		}
+		upload.Observe(sla, s.server, p)
+		perf = p
+	case PROBE_DOWNLOAD:
+		run := download.Run
+		if s.Sweep {
+			run = download.Sweep
+		}
+		if s.Overview {
+			run = download.Overview
+		}
+		// Date defaults to the day of this run
+		r := download.RunAll(ctx, s.download, s.downloadOpts, run)
+		download.ObserveReport(sla, r)
+		perf = r
+	}
+
+	raw, e := json.Marshal(perf)
+	if e != nil {
+		// E.g. NaN in a measurement, keep the run as failed
+		// instead of taking the daemon down
+		log.Printf("%s: %s result: %s", s.Name, s.Probe, e)
+		switch r := perf.(type) {
+		case download.Report:
+			for i := range r.Servers {
+				r.Servers[i].Perf = download.Failed(e)
+			}
+		default:
+			perf = upload.Failed(e)
+		}
+		raw, _ = json.Marshal(perf)
+	}
+	return Result{
+		Name:   s.Name,
+		Probe:  s.Probe,
+		Server: s.server,
+		Begin:  begin,
+		Time:   duration.MilliSeconds(time.Since(begin)),
+		Perf:   raw,
+	}
+}
+
+// Run every Interval until ctx is done, the first run is
+// Interval after the last one in history.
+func (s *Schedule) Loop(ctx context.Context, h *History) {
+	next := time.Now()
+	if last, ok := h.Last(s.Name); ok {
+		next = last.Begin.Add(s.Interval.Duration)
+	}
+	for {
+		timer := time.NewTimer(next.Sub(time.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		res := s.Run(ctx)
+		if ctx.Err() != nil {
+			// Interrupted by shutdown, not a result
+			return
+		}
+		log.Printf("%s: %s done in %.0fms", s.Name, s.Probe, res.Time)
+		if e := h.Add(res); e != nil {
+			log.Printf("%s: history: %s", s.Name, e)
+		}
+		next = res.Begin.Add(s.Interval.Duration)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"sla/lib/download"
+	"sla/lib/nagios"
+	"strings"
+)
+
+// Read the JSON written by download, either one Perf or a Report
+func readResult(file string) (download.Report, error) {
+	var r download.Report
+	b, e := ioutil.ReadFile(file)
+	if e != nil {
+		return r, e
+	}
+	if e := json.Unmarshal(b, &r); e != nil {
+		return r, e
+	}
+	if len(r.Servers) > 0 {
+		return r, nil
+	}
+	var perf download.Perf
+	if e := json.Unmarshal(b, &perf); e != nil {
+		return r, e
+	}
+	r.Servers = []download.ServerPerf{{Perf: perf}}
+	return r, nil
+}
+
+// Nagios plugin, returns the exit code
+func check(args []string) int {
+	var file, configPath string
+	var o download.Options
+	var speed, avg, completion, errs nagios.Threshold
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.StringVar(&file, "f", "", "/Path/to/result.json written by download, empty runs the download")
+	fs.StringVar(&configPath, "c", "./config.json", "/Path/to/config.json")
+	fs.StringVar(&o.Mode, "mode", download.MODE_ARTICLE, "Command per segment: article, body, head or stat")
+	speed.Flags(fs, "speed", "KB/s", -1, 100)
+	avg.Flags(fs, "latency", "ms", -1, -1)
+	completion.Flags(fs, "completion", "percent", 100, 95)
+	errs.Flags(fs, "errors", "count", -1, 0)
+
+	// A bad flag is UNKNOWN, exit code 2 means CRITICAL to Nagios
+	c := nagios.New("SLA/DOWNLOAD")
+	if e := fs.Parse(args); e != nil {
+		c.Unknown(e.Error())
+		return c.Write(os.Stdout)
+	}
+	var r download.Report
+	if file != "" {
+		var e error
+		if r, e = readResult(file); e != nil {
+			c.Unknown(e.Error())
+			return c.Write(os.Stdout)
+		}
+	} else {
+		var e error
+		if C, e = loadConfig(configPath); e != nil {
+			c.Unknown(e.Error())
+			return c.Write(os.Stdout)
+		}
+		r = download.RunAll(context.Background(), C, o, download.Run)
+	}
+
+	for _, s := range r.Servers {
+		prefix := ""
+		if len(r.Servers) > 1 {
+			prefix = s.Name + " "
+		}
+		c.Min(prefix+"speed", s.TotalKBsec, "KB", speed)
+		c.Max(prefix+"latency", s.Summary.Time.Mean, "ms", avg)
+		c.Min(prefix+"completion", s.Completion, "%", completion)
+		c.Max(prefix+"errors", float64(len(s.Error)), "", errs)
+		if len(s.Error) > 0 {
+			c.Info(prefix + strings.Join(s.Error, ", "))
+		}
+	}
+	return c.Write(os.Stdout)
+}
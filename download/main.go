@@ -1,42 +1,21 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
-	"fmt"
-	"github.com/chrisfarms/yenc"
-	"io"
-	"net/textproto"
 	"os"
-	"sla/lib/duration"
-	"sla/lib/nntp"
-	"sla/lib/nzb"
-	"sla/lib/stream"
-	"strings"
-	"time"
+	"sla/lib/download"
+	"sla/lib/metrics"
 )
 
-type Config struct {
-	Address string // server:port
-	User    string
-	Pass    string
-	NzbDir  string
-}
-
-type Perf struct {
-	Conn  float64
-	Auth  float64
-	Arts  []float64
-	KBsec []float64
-	Error []string
-}
+var C download.Config
 
-var C Config
+// Path for the node_exporter textfile collector, empty to skip
+var promFile string
 
-func loadConfig(file string) (Config, error) {
-	var c Config
+func loadConfig(file string) (download.Config, error) {
+	var c download.Config
 	r, e := os.Open(file)
 	if e != nil {
 		return c, e
@@ -45,133 +24,89 @@ func loadConfig(file string) (Config, error) {
 	return c, e
 }
 
-func fail(e error) {
-	enc := json.NewEncoder(os.Stdout)
+// Write r in Prometheus text format to promFile
+func writeMetrics(r download.Report) error {
+	if promFile == "" {
+		return nil
+	}
+	reg := metrics.NewRegistry()
+	download.ObserveReport(metrics.NewSLA(reg), r)
+	return reg.WriteFile(promFile)
+}
 
-	if ew := enc.Encode(Perf{
-		Arts: []float64{},
-		KBsec: []float64{},
-		Error: []string{e.Error()},
-	}); ew != nil {
+// Print out as JSON and write metrics of r
+func report(r download.Report, out interface{}) error {
+	if e := writeMetrics(r); e != nil {
+		return e
+	}
+	return json.NewEncoder(os.Stdout).Encode(out)
+}
+
+func fail(e error) {
+	perf := download.Failed(e)
+	// Best effort, the JSON below still reports the error
+	writeMetrics(download.Report{Servers: []download.ServerPerf{{Name: C.Address, Perf: perf}}})
+	if ew := json.NewEncoder(os.Stdout).Encode(perf); ew != nil {
 		panic(ew)
 	}
 	os.Exit(1)
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(check(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "munin" {
+		os.Exit(muninPlugin(os.Args[2:]))
+	}
+
 	var e error
-	var verbose, skipyenc bool
-	var configPath, date string
-	flag.BoolVar(&verbose, "v", false, "Verbosity")
-	flag.BoolVar(&skipyenc, "y", false, "Skip yEnc decode")
+	var o download.Options
+	var sweepAll bool
+	var overview bool
+	var configPath string
+	flag.BoolVar(&o.Verbose, "v", false, "Verbosity")
+	flag.BoolVar(&o.SkipYenc, "y", false, "Skip yEnc decode")
 	flag.StringVar(&configPath, "c", "./config.json", "/Path/to/config.json")
-	flag.StringVar(&date, "d", "", "YYYY-mm-dd to download from nzbdir")
+	flag.StringVar(&o.Date, "d", "", "YYYY-mm-dd to download from nzbdir")
+	flag.StringVar(&o.Mode, "mode", download.MODE_ARTICLE, "Command per segment: article, body, head or stat")
+	flag.IntVar(&o.Pipeline, "pipeline", 1, "Commands in flight per connection")
+	flag.BoolVar(&sweepAll, "sweep", false, "Check retention of every NZB in nzbdir")
+	flag.IntVar(&o.Samples, "sample", 10, "Segments to check per NZB with -sweep (0=all)")
+	flag.BoolVar(&overview, "overview", false, "Check the posts of -d appear in the group overview")
+	flag.Int64Var(&o.Window, "window", download.DEFAULT_WINDOW, "Newest articles per group scanned with -overview")
+	flag.StringVar(&promFile, "prom", "", "/Path/to/sla.prom for the node_exporter textfile collector")
 	flag.Parse()
 
 	C, e = loadConfig(configPath)
 	if e != nil {
 		fail(e)
 	}
-	if !strings.HasSuffix(C.NzbDir, "/") {
-		C.NzbDir += "/"
-	}
-	if date == "" {
-		// default to today
-		date = time.Now().Format("2006-01-02")
-	}
-	if verbose {
-		fmt.Printf("Config=%+v Date=%+v\n", C, date)
-	}
 
-	// Force valid date pattern
-	if _, e := time.Parse("2006-01-02", date); e != nil {
-		fail(e)
-	}
-
-	fd, e := os.Open(C.NzbDir + date + ".nzb")
-	if e != nil {
-		fail(e)
-	}
-	defer fd.Close()
-	arts, e := nzb.Read(fd)
-	if e != nil {
-		fail(e)
+	run := download.Run
+	if sweepAll {
+		run = download.Sweep
 	}
-
-	if verbose {
-		fmt.Println("Connecting to nntp..")
+	if overview {
+		run = download.Overview
 	}
-	conn := nntp.New(C.Address, "1", verbose)
-	conn.Verbose = verbose
-	perfBegin := time.Now()
-	var perfInit, perfAuth time.Time
-	{
-		defer conn.Close()
-		if e := conn.Init(); e != nil {
+	if len(C.Servers) > 0 {
+		// Combined document of all servers
+		r := download.RunAll(context.Background(), C, o, run)
+		if e := report(r, r); e != nil {
 			fail(e)
 		}
-		perfInit = time.Now()
-		if e := conn.Auth(C.User, C.Pass); e != nil {
-			fail(e)
-		}
-		perfAuth = time.Now()
+		return
 	}
 
-	perfArts := []float64{}
-	KBsecs := []float64{}
-	lastPerf := time.Now()
-	buf := new(bytes.Buffer)
-	for _, segment := range arts.File.Segments.Segment {
-		buf.Reset()
-		conn.Article(segment.Msgid)
-
-		counter := stream.NewCountReader(conn.GetReader())
-		rawread := bufio.NewReader(counter)
-
-		_, e = textproto.NewReader(rawread).ReadMIMEHeader()
-		if e != nil {
-			fail(e)
-		}
-
-		if _, e := io.Copy(buf, rawread); e != nil {
-			fail(e)
-		}
-		n := counter.ReadReset()
-		if int64(n) <= segment.Bytes {
-			panic(fmt.Errorf("ByteCount mismatch, expect>%d recv=%d", segment.Bytes, n))
-		}
-
-		if !skipyenc {
-			if _, e = yenc.Decode(buf); e != nil {
-				fmt.Printf("%+v\n", string(buf.Bytes()))
-				fail(e)
-			}
-		}
-
-		now := time.Now()
-		diff := now.Sub(lastPerf)
-		kbSec := float64(n/1024) / diff.Seconds()
-
-		if verbose {
-			fmt.Println(fmt.Sprintf(
-				"Download %s (%d bytes in %s with %f KB/s)",
-				segment.Msgid, n, diff.String(), kbSec,
-			))
-		}
-
-		KBsecs = append(KBsecs, kbSec)
-		perfArts = append(perfArts, duration.MilliSeconds(diff))
-		lastPerf = now
+	// Perf of the single server as before
+	s := download.Servers(C)[0]
+	perf, e := run(context.Background(), C, s, o)
+	if e != nil {
+		fail(e)
 	}
-
-	enc := json.NewEncoder(os.Stdout)
-	if e := enc.Encode(Perf{
-		Conn:  duration.MilliSeconds(perfInit.Sub(perfBegin)),
-		Auth:  duration.MilliSeconds(perfAuth.Sub(perfInit)),
-		Arts:  perfArts,
-		KBsec: KBsecs,
-		Error: []string{},
-	}); e != nil {
+	r := download.Report{Servers: []download.ServerPerf{{Name: s.Name, Address: s.Address, Perf: perf}}}
+	if e := report(r, perf); e != nil {
 		fail(e)
 	}
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sla/lib/download"
+	"sla/lib/munin"
+)
+
+func graphs(r download.Report) []munin.Graph {
+	latency := munin.Graph{Name: "sla_download_latency", Title: "SLA download latency", VLabel: "ms", Category: "sla"}
+	speed := munin.Graph{Name: "sla_download_speed", Title: "SLA download speed", VLabel: "KB/s", Category: "sla"}
+	completion := munin.Graph{Name: "sla_download_completion", Title: "SLA download completion", VLabel: "%", Category: "sla"}
+	errs := munin.Graph{Name: "sla_download_errors", Title: "SLA download errors", VLabel: "count", Category: "sla"}
+
+	for _, s := range r.Servers {
+		name, label := "", ""
+		if len(r.Servers) > 1 {
+			name, label = s.Name+"_", s.Name+" "
+		}
+		latency.Add(name+"conn", label+"Connect", s.Conn)
+		latency.Add(name+"auth", label+"Auth", s.Auth)
+		latency.Add(name+"p50", label+"Article p50", s.Summary.Time.P50)
+		latency.Add(name+"p90", label+"Article p90", s.Summary.Time.P90)
+		latency.Add(name+"p99", label+"Article p99", s.Summary.Time.P99)
+		speed.Add(name+"speed", label+"All conns", s.TotalKBsec)
+		completion.Add(name+"completion", label+"Segments found", s.Completion)
+		errs.Add(name+"errors", label+"Errors", float64(len(s.Error)))
+		errs.Add(name+"missing", label+"Missing", float64(s.Missing))
+		errs.Add(name+"corrupt", label+"Corrupt", float64(s.Corrupt))
+	}
+	return []munin.Graph{latency, speed, completion, errs}
+}
+
+// Munin plugin, `munin` prints values and `munin config` the graphs
+func muninPlugin(args []string) int {
+	var file string
+	def := os.Getenv("result")
+	if def == "" {
+		def = "/tmp/sla.download.json.tmp"
+	}
+	fs := flag.NewFlagSet("munin", flag.ExitOnError)
+	fs.StringVar(&file, "f", def, "/Path/to/result.json written by download (env.result)")
+	fs.Parse(args)
+
+	r, e := readResult(file)
+	if fs.Arg(0) == "config" {
+		if e != nil {
+			// Graphs of a single server until the first result
+			r = download.Report{Servers: []download.ServerPerf{{}}}
+		}
+		e = munin.Config(os.Stdout, graphs(r))
+	} else if e == nil {
+		e = munin.Values(os.Stdout, graphs(r))
+	}
+	if e != nil {
+		fmt.Fprintln(os.Stderr, e)
+		return 1
+	}
+	return 0
+}
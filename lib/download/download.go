@@ -0,0 +1,393 @@
+// Download the NZB posted by upload and measure availability,
+// speed and integrity of every segment.
+package download
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sla/lib/duration"
+	"sla/lib/manifest"
+	"sla/lib/nntp"
+	"sla/lib/nzb"
+	"sla/lib/stats"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Address string // server:port, used when Servers is empty
+	User    string
+	Pass    string
+	NzbDir  string
+	TLS     nntp.TLS
+	Servers []nntp.Server // Download from each
+
+	DialTimeout  duration.Duration
+	ReadTimeout  duration.Duration
+	WriteTimeout duration.Duration
+	Deadline     duration.Duration // Max duration of run per server
+	Conns        int               // Parallel connections, used when Servers is empty
+	Capabilities []string          // Expected, used when Servers is empty
+}
+
+// Servers of c, or the single server of older configs
+func Servers(c Config) []nntp.Server {
+	return nntp.Legacy(c.Servers, c.Address, c.User, c.Pass, c.TLS, c.Conns, c.Capabilities)
+}
+
+// Settings of one run
+type Options struct {
+	Date     string // YYYY-mm-dd to download, default today
+	Mode     string // Command per segment, default MODE_ARTICLE
+	Pipeline int    // Commands in flight per connection, default 1
+	Samples  int    // Segments to check per NZB with Sweep (0=all)
+	Window   int64  // Newest articles per group scanned by Overview (0=DEFAULT_WINDOW)
+	Verbose  bool
+	SkipYenc bool
+}
+
+type Perf struct {
+	Mode       string    // Command used per segment
+	Pipeline   int       // Commands in flight per connection
+	Conn       float64   // First connection
+	TLS        float64   // First connection
+	Auth       float64   // First connection
+	Arts       []float64 // ms per segment
+	TTFB       []float64 // ms from command until response line per segment
+	KBsec      []float64
+	Summary    Summary // Of found segments
+	Segments   []SegmentPerf
+	Found      int     // Segments downloaded
+	Missing    int     // Segments not on server
+	Corrupt    int     // Segments damaged
+	Completion float64 // Found/Segments in percent
+	TotalKBsec float64 // All conns combined
+	Conns      []nntp.ConnPerf
+	Verify     VerifyPerf
+	Caps       nntp.Capabilities // Of the first connection
+	Retention  []RetentionPerf   // Only filled by Sweep
+	Overview   []OverviewPerf    // Only filled by Overview
+	Error      []string
+	Failures   []nntp.Failure // Error classified, same order
+}
+
+// Distribution of found segments
+type Summary struct {
+	Time      stats.Summary // ms per segment
+	TTFB      stats.Summary // ms from command until response line (server lookup)
+	FirstByte stats.Summary // ms from command until first byte of the article
+	Transfer  stats.Summary // ms from first byte until terminator (pipe)
+	KBsec     stats.Summary
+}
+
+func summarize(perf Perf) Summary {
+	var times, ttfb, first, transfer, kbsec []float64
+	for idx, segment := range perf.Segments {
+		if segment.State != STATE_FOUND {
+			continue
+		}
+		times = append(times, perf.Arts[idx])
+		ttfb = append(ttfb, segment.StatusLine-segment.Sent)
+		if segment.FirstByte > 0 {
+			// No article transferred in stat mode
+			first = append(first, segment.FirstByte-segment.Sent)
+			transfer = append(transfer, segment.Terminator-segment.FirstByte)
+		}
+		kbsec = append(kbsec, perf.KBsec[idx])
+	}
+	return Summary{
+		Time:      stats.Summarize(times),
+		TTFB:      stats.Summarize(ttfb),
+		FirstByte: stats.Summarize(first),
+		Transfer:  stats.Summarize(transfer),
+		KBsec:     stats.Summarize(kbsec),
+	}
+}
+
+// Availability of a single segment
+type SegmentPerf struct {
+	Number int
+	Msgid  string
+	State  string // STATE_FOUND, STATE_MISSING or STATE_CORRUPT
+	Status int    // NNTP response code
+	Error  string // Why it's corrupt
+
+	// ms since begin of segment, 0 when not reached
+	Sent       float64 // Command flushed
+	StatusLine float64 // Response line read
+	FirstByte  float64 // First byte of the article
+	Terminator float64 // End of the article read
+}
+
+// Perf of one server in Report
+type ServerPerf struct {
+	Name    string
+	Address string
+	Perf
+}
+
+// Results of every server
+type Report struct {
+	Servers []ServerPerf
+}
+
+// Run, Sweep or Overview
+type RunFunc func(ctx context.Context, c Config, s nntp.Server, o Options) (Perf, error)
+
+// Call run for every server of c one after another, so they
+// don't compete for bandwidth.
+func RunAll(ctx context.Context, c Config, o Options, run RunFunc) Report {
+	r := Report{Servers: []ServerPerf{}}
+	for _, s := range Servers(c) {
+		perf, e := run(ctx, c, s, o)
+		if e != nil {
+			perf = Failed(e)
+		}
+		r.Servers = append(r.Servers, ServerPerf{Name: s.Name, Address: s.Address, Perf: perf})
+	}
+	return r
+}
+
+// Perf of a run that stopped with e
+func Failed(e error) Perf {
+	return Perf{
+		Arts:     []float64{},
+		TTFB:     []float64{},
+		KBsec:    []float64{},
+		Segments: []SegmentPerf{},
+		Conns:    []nntp.ConnPerf{},
+		Error:    []string{e.Error()},
+		Failures: []nntp.Failure{nntp.NewFailure(e)},
+	}
+}
+
+// Messages and classes of errs for Perf, other replaces
+// nntp.FAIL_OTHER.
+func classify(errs []error, other string) ([]string, []nntp.Failure) {
+	msgs := []string{}
+	failures := []nntp.Failure{}
+	for _, e := range errs {
+		f := nntp.NewFailure(e)
+		if f.Class == nntp.FAIL_OTHER {
+			f.Class = other
+		}
+		msgs = append(msgs, e.Error())
+		failures = append(failures, f)
+	}
+	return msgs, failures
+}
+
+// Defaults and validation shared by Run, Sweep and Overview
+func prepare(ctx context.Context, c *Config, s *nntp.Server, o *Options) (context.Context, context.CancelFunc, error) {
+	*s = s.Normalize()
+	if !strings.HasSuffix(c.NzbDir, "/") {
+		c.NzbDir += "/"
+	}
+	if o.Date == "" {
+		// default to today
+		o.Date = time.Now().Format("2006-01-02")
+	}
+	if o.Mode == "" {
+		o.Mode = MODE_ARTICLE
+	}
+	if o.Mode != MODE_ARTICLE && o.Mode != MODE_BODY && o.Mode != MODE_HEAD && o.Mode != MODE_STAT {
+		return ctx, func() {}, fmt.Errorf("Invalid mode: %s", o.Mode)
+	}
+	if o.Pipeline < 1 {
+		o.Pipeline = 1
+	}
+	if o.Mode == MODE_HEAD || o.Mode == MODE_STAT {
+		// Nothing to decode
+		o.SkipYenc = true
+	}
+	if o.Verbose {
+		fmt.Printf("Config=%+v Server=%+v Options=%+v\n", *c, *s, *o)
+	}
+
+	if c.Deadline.Duration > 0 {
+		ctx, cancel := context.WithTimeout(ctx, c.Deadline.Duration)
+		return ctx, cancel, nil
+	}
+	return ctx, func() {}, nil
+}
+
+// Download the NZB of o.Date from c.NzbDir on s
+func Run(ctx context.Context, c Config, s nntp.Server, o Options) (Perf, error) {
+	ctx, cancel, e := prepare(ctx, &c, &s, &o)
+	defer cancel()
+	if e != nil {
+		return Perf{}, e
+	}
+
+	// Force valid date pattern
+	if _, e := time.Parse("2006-01-02", o.Date); e != nil {
+		return Perf{}, e
+	}
+
+	fd, e := os.Open(c.NzbDir + o.Date + ".nzb")
+	if e != nil {
+		return Perf{}, e
+	}
+	defer fd.Close()
+	arts, e := nzb.Read(fd)
+	if e != nil {
+		return Perf{}, e
+	}
+
+	segments := arts.Segments()
+	if len(segments) == 0 {
+		return Perf{}, fmt.Errorf("No segments in %s.nzb", o.Date)
+	}
+	conns := s.Conns
+	if conns > len(segments) {
+		conns = len(segments)
+	}
+
+	// Reassemble into tmpfile if we know what upload posted
+	var out *os.File
+	var verifyPerf VerifyPerf
+	mf, ok, e := manifest.Open(c.NzbDir + o.Date + ".json")
+	if e != nil {
+		return Perf{}, e
+	}
+	if ok && !o.SkipYenc {
+		verifyPerf.Checked = true
+		out, e = ioutil.TempFile("", "sla-download-")
+		if e != nil {
+			return Perf{}, e
+		}
+		defer os.Remove(out.Name())
+		defer out.Close()
+	}
+
+	jobs := make(chan job, len(segments))
+	for idx, segment := range segments {
+		j := job{Idx: idx, Segment: segment}
+		if part, ok := mf.Part(segment.Number); verifyPerf.Checked && ok {
+			j.Part = &part
+		}
+		jobs <- j
+	}
+	close(jobs)
+	results := make(chan result, len(segments))
+
+	if o.Verbose {
+		fmt.Printf("Connecting to %s with %d conns..\n", s.Name, conns)
+	}
+	workers := make([]*worker, conns)
+	for i := 0; i < conns; i++ {
+		w, e := newWorker(c, s, i+1, o.Mode, o.Verbose, o.SkipYenc)
+		if e != nil {
+			return Perf{}, e
+		}
+		w.Pipeline = o.Pipeline
+		if out != nil {
+			w.Out = out
+		}
+		workers[i] = w
+	}
+
+	if e := pool(ctx, workers, func(ctx context.Context, w *worker) error {
+		return w.Run(ctx, jobs, results)
+	}); e != nil {
+		return Perf{}, e
+	}
+	close(results)
+
+	perfArts := make([]float64, len(segments))
+	perfTTFB := make([]float64, len(segments))
+	KBsecs := make([]float64, len(segments))
+	segmentPerf := make([]SegmentPerf, len(segments))
+	var first, last time.Time
+	var total uint64
+	var found, missing, corrupted int
+	for res := range results {
+		diff := res.End.Sub(res.Begin)
+		if diff > 0 {
+			KBsecs[res.Idx] = float64(res.Bytes/1024) / diff.Seconds()
+		}
+		perfArts[res.Idx] = duration.MilliSeconds(diff)
+		perfTTFB[res.Idx] = duration.Between(res.Timing.Sent, res.Timing.Status)
+		segmentPerf[res.Idx] = SegmentPerf{
+			Number: segments[res.Idx].Number,
+			Msgid:  segments[res.Idx].Msgid,
+			State:  res.State,
+			Status: res.Status,
+			Error:  res.Error,
+
+			Sent:       duration.Between(res.Begin, res.Timing.Sent),
+			StatusLine: duration.Between(res.Begin, res.Timing.Status),
+			FirstByte:  duration.Between(res.Begin, res.Timing.FirstByte),
+			Terminator: duration.Between(res.Begin, res.Timing.Terminator),
+		}
+		switch res.State {
+		case STATE_FOUND:
+			found++
+		case STATE_MISSING:
+			missing++
+		case STATE_CORRUPT:
+			corrupted++
+		}
+		if res.Verified {
+			verifyPerf.Parts++
+		}
+
+		total += res.Bytes
+		if first.IsZero() || res.Begin.Before(first) {
+			first = res.Begin
+		}
+		if res.End.After(last) {
+			last = res.End
+		}
+	}
+	totalKBsec := float64(0)
+	if wall := last.Sub(first); wall > 0 {
+		totalKBsec = float64(total/1024) / wall.Seconds()
+	}
+
+	completion := float64(0)
+	if len(segments) > 0 {
+		completion = float64(found) / float64(len(segments)) * 100
+	}
+
+	connPerf := []nntp.ConnPerf{}
+	for _, w := range workers {
+		connPerf = append(connPerf, w.Perf)
+	}
+
+	var perfErrs []error
+	caps := workers[0].conn.Caps
+	if e := caps.Expect(s.Capabilities); e != nil {
+		perfErrs = append(perfErrs, e)
+	}
+	if verifyPerf.Checked {
+		perfErrs = append(perfErrs, verify(out, mf, &verifyPerf)...)
+	}
+	errs, failures := classify(perfErrs, FAIL_VERIFY)
+
+	perf := Perf{
+		Conn:       connPerf[0].Conn,
+		TLS:        connPerf[0].TLS,
+		Auth:       connPerf[0].Auth,
+		Caps:       caps,
+		Mode:       o.Mode,
+		Pipeline:   o.Pipeline,
+		Arts:       perfArts,
+		TTFB:       perfTTFB,
+		KBsec:      KBsecs,
+		Segments:   segmentPerf,
+		Found:      found,
+		Missing:    missing,
+		Corrupt:    corrupted,
+		Completion: completion,
+		TotalKBsec: totalKBsec,
+		Conns:      connPerf,
+		Verify:     verifyPerf,
+		Error:      errs,
+		Failures:   failures,
+	}
+	perf.Summary = summarize(perf)
+	return perf, nil
+}
@@ -0,0 +1,84 @@
+package download
+
+import (
+	"fmt"
+	"sla/lib/metrics"
+	"time"
+)
+
+// Upper bounds in days of the retention age buckets, a fixed
+// set so the label does not grow with every day of uploads
+var RETENTION_BUCKETS = []int{1, 7, 30, 365, 1000}
+
+// Completion of the sweep samples within one age bucket
+type retentionBucket struct {
+	Age        string // e.g. 7d, +Inf beyond the last bucket
+	Completion float64
+}
+
+// Combine the NZBs of a sweep per age bucket, empty buckets
+// are skipped.
+func retentionBuckets(retention []RetentionPerf) []retentionBucket {
+	checked := make([]int, len(RETENTION_BUCKETS)+1)
+	found := make([]int, len(RETENTION_BUCKETS)+1)
+	for _, ret := range retention {
+		idx := len(RETENTION_BUCKETS)
+		for i, le := range RETENTION_BUCKETS {
+			if ret.Age <= le {
+				idx = i
+				break
+			}
+		}
+		checked[idx] += ret.Checked
+		found[idx] += ret.Found
+	}
+	out := []retentionBucket{}
+	for i := range checked {
+		if checked[i] == 0 {
+			continue
+		}
+		age := "+Inf"
+		if i < len(RETENTION_BUCKETS) {
+			age = fmt.Sprintf("%dd", RETENTION_BUCKETS[i])
+		}
+		out = append(out, retentionBucket{Age: age, Completion: float64(found[i]) / float64(checked[i]) * 100})
+	}
+	return out
+}
+
+// Add perf of one run against server to m
+func Observe(m *metrics.SLA, server string, perf Perf) {
+	l := metrics.Label(server, "download")
+
+	for _, c := range perf.Conns {
+		m.Connect.Observe(l, c.Conn/1000)
+		m.TLS.Observe(l, c.TLS/1000)
+		m.Auth.Observe(l, c.Auth/1000)
+	}
+	for idx, ms := range perf.Arts {
+		if perf.Segments[idx].State != STATE_FOUND {
+			continue
+		}
+		m.Article.Observe(l, ms/1000)
+		m.Throughput.Observe(l, perf.KBsec[idx]*1024)
+	}
+	m.Speed.Set(l, perf.TotalKBsec*1024)
+	m.Articles.Add(metrics.Label(server, "download", "state", STATE_FOUND), float64(perf.Found))
+	m.Articles.Add(metrics.Label(server, "download", "state", STATE_MISSING), float64(perf.Missing))
+	m.Articles.Add(metrics.Label(server, "download", "state", STATE_CORRUPT), float64(perf.Corrupt))
+	if len(perf.Retention) == 0 {
+		m.Completion.Set(l, perf.Completion)
+	}
+	for _, b := range retentionBuckets(perf.Retention) {
+		m.Completion.Set(metrics.Label(server, "download", "age", b.Age), b.Completion)
+	}
+	m.Errors.Add(l, float64(len(perf.Error)))
+	m.Timestamp.Set(l, float64(time.Now().Unix()))
+}
+
+// Add every server of r to m
+func ObserveReport(m *metrics.SLA, r Report) {
+	for _, s := range r.Servers {
+		Observe(m, s.Name, s.Perf)
+	}
+}
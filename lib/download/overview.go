@@ -0,0 +1,191 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sla/lib/duration"
+	"sla/lib/nntp"
+	"sla/lib/nzb"
+	"time"
+)
+
+const MODE_OVER = "over" // Overview of the groups, only used by Overview
+
+const FAIL_OVERVIEW = "overview"
+
+// Newest articles per group scanned by Overview
+const DEFAULT_WINDOW = 100000
+
+// Overview service of one group
+type OverviewPerf struct {
+	Group      string
+	Count      int64   // Estimated articles according to GROUP
+	Low        int64   // Lowest article number
+	High       int64   // Highest article number
+	GroupTime  float64 // ms for GROUP
+	TTFB       float64 // ms from OVER until response line
+	Transfer   float64 // ms from first byte until terminator
+	Lines      int     // Overview lines read
+	Posts      int     // Segments posted to the group
+	Found      int     // Posts in the overview
+	Completion float64 // Found/Posts in percent
+}
+
+// Select group and scan the overview of its newest window
+// articles for msgids.
+func (w *worker) Overview(ctx context.Context, group string, msgids []string, window int64) (OverviewPerf, error) {
+	begin := time.Now()
+	g, e := w.conn.GroupContext(ctx, group)
+	if e != nil {
+		return OverviewPerf{}, e
+	}
+	perf := OverviewPerf{
+		Group:     g.Name,
+		Count:     g.Count,
+		Low:       g.Low,
+		High:      g.High,
+		GroupTime: duration.MilliSeconds(time.Since(begin)),
+		Posts:     len(msgids),
+	}
+	low := g.Low
+	if window > 0 && g.High-window+1 > low {
+		low = g.High - window + 1
+	}
+	if g.Count == 0 || g.High < low {
+		// Empty group, no overview to ask for
+		return perf, nil
+	}
+
+	pending := make(map[string]bool, len(msgids))
+	for _, msgid := range msgids {
+		pending[msgid] = true
+	}
+	r, e := w.conn.OverContext(ctx, fmt.Sprintf("%d-%d", low, g.High))
+	if e != nil {
+		return perf, e
+	}
+	for {
+		o, e := r.Next()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return perf, e
+		}
+		perf.Lines++
+		if pending[o.MessageID] {
+			delete(pending, o.MessageID)
+			perf.Found++
+		}
+	}
+	t := w.conn.Timing
+	perf.TTFB = duration.Between(t.Sent, t.Status)
+	perf.Transfer = duration.Between(t.FirstByte, t.Terminator)
+	if perf.Posts > 0 {
+		perf.Completion = float64(perf.Found) / float64(perf.Posts) * 100
+	}
+	if w.Verbose {
+		fmt.Printf("C(%s) Overview %s %d-%d: %d of %d posts\n", w.Perf.Name, g.Name, low, g.High, perf.Found, perf.Posts)
+	}
+	return perf, nil
+}
+
+// Confirm the posts of o.Date appear in the overview of their
+// groups on s.
+func Overview(ctx context.Context, c Config, s nntp.Server, o Options) (Perf, error) {
+	ctx, cancel, e := prepare(ctx, &c, &s, &o)
+	defer cancel()
+	if e != nil {
+		return Perf{}, e
+	}
+	if _, e := time.Parse("2006-01-02", o.Date); e != nil {
+		return Perf{}, e
+	}
+	if o.Window == 0 {
+		o.Window = DEFAULT_WINDOW
+	}
+
+	fd, e := os.Open(c.NzbDir + o.Date + ".nzb")
+	if e != nil {
+		return Perf{}, e
+	}
+	arts, e := nzb.Read(fd)
+	fd.Close()
+	if e != nil {
+		return Perf{}, e
+	}
+
+	// Msgids per group in order of appearance
+	var groups []string
+	posts := make(map[string][]string)
+	for _, f := range arts.Files {
+		for _, group := range f.Groups {
+			if _, ok := posts[group]; !ok {
+				groups = append(groups, group)
+			}
+			for _, segment := range f.Segments {
+				posts[group] = append(posts[group], segment.Msgid)
+			}
+		}
+	}
+	if len(groups) == 0 {
+		return Perf{}, fmt.Errorf("No groups in %s.nzb", o.Date)
+	}
+
+	w, e := newWorker(c, s, 1, o.Mode, o.Verbose, true)
+	if e != nil {
+		return Perf{}, e
+	}
+	overview := []OverviewPerf{}
+	if e := pool(ctx, []*worker{w}, func(ctx context.Context, w *worker) error {
+		for _, group := range groups {
+			perf, e := w.Overview(ctx, group, posts[group], o.Window)
+			if e != nil {
+				return e
+			}
+			overview = append(overview, perf)
+		}
+		return nil
+	}); e != nil {
+		return Perf{}, e
+	}
+
+	var perfErrs []error
+	caps := w.conn.Caps
+	if e := caps.Expect(s.Capabilities); e != nil {
+		perfErrs = append(perfErrs, e)
+	}
+	var total, found int
+	for _, perf := range overview {
+		total += perf.Posts
+		found += perf.Found
+		if perf.Found < perf.Posts {
+			perfErrs = append(perfErrs, fmt.Errorf("%s: %d of %d posts not in overview", perf.Group, perf.Posts-perf.Found, perf.Posts))
+		}
+	}
+	completion := float64(0)
+	if total > 0 {
+		completion = float64(found) / float64(total) * 100
+	}
+	errs, failures := classify(perfErrs, FAIL_OVERVIEW)
+	return Perf{
+		Conn:       w.Perf.Conn,
+		TLS:        w.Perf.TLS,
+		Auth:       w.Perf.Auth,
+		Caps:       caps,
+		Mode:       MODE_OVER,
+		Arts:       []float64{},
+		TTFB:       []float64{},
+		KBsec:      []float64{},
+		Segments:   []SegmentPerf{},
+		Found:      found,
+		Missing:    total - found,
+		Completion: completion,
+		Conns:      []nntp.ConnPerf{w.Perf},
+		Overview:   overview,
+		Error:      errs,
+		Failures:   failures,
+	}, nil
+}
@@ -0,0 +1,165 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sla/lib/nntp"
+	"sla/lib/nzb"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Completion of one historical NZB
+type RetentionPerf struct {
+	Date       string  // YYYY-mm-dd of upload
+	Age        int     // Days since upload
+	Segments   int     // Segments in NZB
+	Checked    int     // Sampled segments
+	Found      int     // Sampled segments available
+	Completion float64 // Found/Checked in percent
+}
+
+// Sample n segments evenly spread over the NZB
+func sample(segments []nzb.Segment, n int) []nzb.Segment {
+	if n <= 0 || n >= len(segments) {
+		return segments
+	}
+	out := make([]nzb.Segment, 0, n)
+	for i := 0; i < n; i++ {
+		out = append(out, segments[i*len(segments)/n])
+	}
+	return out
+}
+
+// Probe o.Samples of every YYYY-mm-dd.nzb in NzbDir on s
+func Sweep(ctx context.Context, c Config, s nntp.Server, o Options) (Perf, error) {
+	ctx, cancel, e := prepare(ctx, &c, &s, &o)
+	defer cancel()
+	if e != nil {
+		return Perf{}, e
+	}
+
+	files, e := ioutil.ReadDir(c.NzbDir)
+	if e != nil {
+		return Perf{}, e
+	}
+
+	var perfErrs []error
+	retention := []RetentionPerf{}
+	jobs := []job{}
+	owner := []int{} // Position in retention per job
+	now := time.Now()
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".nzb") {
+			continue
+		}
+		date := strings.TrimSuffix(f.Name(), ".nzb")
+		day, e := time.Parse("2006-01-02", date)
+		if e != nil {
+			// Not written by upload
+			continue
+		}
+
+		fd, e := os.Open(c.NzbDir + f.Name())
+		if e != nil {
+			perfErrs = append(perfErrs, e)
+			continue
+		}
+		arts, e := nzb.Read(fd)
+		fd.Close()
+		if e != nil {
+			perfErrs = append(perfErrs, fmt.Errorf("%s: %w", f.Name(), e))
+			continue
+		}
+
+		segments := arts.Segments()
+		checked := sample(segments, o.Samples)
+		for _, segment := range checked {
+			jobs = append(jobs, job{Idx: len(jobs), Segment: segment})
+			owner = append(owner, len(retention))
+		}
+		retention = append(retention, RetentionPerf{
+			Date:     date,
+			Age:      int(now.Sub(day).Hours() / 24),
+			Segments: len(segments),
+			Checked:  len(checked),
+		})
+	}
+	if len(jobs) == 0 {
+		return Perf{}, fmt.Errorf("No NZBs to sweep in %s", c.NzbDir)
+	}
+
+	conns := s.Conns
+	if conns > len(jobs) {
+		conns = len(jobs)
+	}
+	queue := make(chan job, len(jobs))
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+	results := make(chan result, len(jobs))
+
+	if o.Verbose {
+		fmt.Printf("Sweep %d articles in %d NZBs on %s with %d conns..\n", len(jobs), len(retention), s.Name, conns)
+	}
+	workers := make([]*worker, conns)
+	for i := 0; i < conns; i++ {
+		w, e := newWorker(c, s, i+1, o.Mode, o.Verbose, o.SkipYenc)
+		if e != nil {
+			return Perf{}, e
+		}
+		w.Pipeline = o.Pipeline
+		workers[i] = w
+	}
+	// Same pipelined path as Run, damaged content counts as missing
+	if e := pool(ctx, workers, func(ctx context.Context, w *worker) error {
+		return w.Run(ctx, queue, results)
+	}); e != nil {
+		return Perf{}, e
+	}
+	close(results)
+
+	for res := range results {
+		if res.State == STATE_FOUND {
+			retention[owner[res.Idx]].Found++
+		}
+	}
+	for i := range retention {
+		if retention[i].Checked > 0 {
+			retention[i].Completion = float64(retention[i].Found) / float64(retention[i].Checked) * 100
+		}
+	}
+	sort.Slice(retention, func(i, j int) bool {
+		return retention[i].Age < retention[j].Age
+	})
+
+	connPerf := []nntp.ConnPerf{}
+	for _, w := range workers {
+		connPerf = append(connPerf, w.Perf)
+	}
+	caps := workers[0].conn.Caps
+	if e := caps.Expect(s.Capabilities); e != nil {
+		perfErrs = append(perfErrs, e)
+	}
+	errs, failures := classify(perfErrs, nntp.FAIL_OTHER)
+	return Perf{
+		Conn:      connPerf[0].Conn,
+		TLS:       connPerf[0].TLS,
+		Auth:      connPerf[0].Auth,
+		Caps:      caps,
+		Arts:      []float64{},
+		TTFB:      []float64{},
+		KBsec:     []float64{},
+		Segments:  []SegmentPerf{},
+		Mode:      o.Mode,
+		Pipeline:  o.Pipeline,
+		Conns:     connPerf,
+		Retention: retention,
+		Error:     errs,
+		Failures:  failures,
+	}, nil
+}
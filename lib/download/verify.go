@@ -0,0 +1,71 @@
+package download
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sla/lib/manifest"
+)
+
+// Class of verify errors in Perf.Failures
+const FAIL_VERIFY = "verify"
+
+// Reassembled ZIP compared against the manifest of upload
+type VerifyPerf struct {
+	Checked bool // Manifest found and compared
+	Parts   int  // Parts matching manifest CRC
+	SHA256  bool // Whole file matches
+	Unique  bool // unique.txt matches
+}
+
+// Check reassembled file f against m
+func verify(f *os.File, m manifest.Manifest, perf *VerifyPerf) []error {
+	var errs []error
+	stat, e := f.Stat()
+	if e != nil {
+		return []error{e}
+	}
+	if stat.Size() != m.Size {
+		errs = append(errs, fmt.Errorf("Verify size mismatch, expect=%d recv=%d", m.Size, stat.Size()))
+	}
+
+	h := sha256.New()
+	if _, e := io.Copy(h, io.NewSectionReader(f, 0, stat.Size())); e != nil {
+		return append(errs, e)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != m.SHA256 {
+		errs = append(errs, fmt.Errorf("Verify sha256 mismatch, expect=%s recv=%s", m.SHA256, sum))
+	} else {
+		perf.SHA256 = true
+	}
+
+	z, e := zip.NewReader(f, stat.Size())
+	if e != nil {
+		return append(errs, e)
+	}
+	for _, zf := range z.File {
+		if zf.Name != "unique.txt" {
+			continue
+		}
+		r, e := zf.Open()
+		if e != nil {
+			return append(errs, e)
+		}
+		unique, e := ioutil.ReadAll(r)
+		r.Close()
+		if e != nil {
+			return append(errs, e)
+		}
+		if string(unique) != m.Unique {
+			errs = append(errs, fmt.Errorf("Verify unique.txt mismatch, expect=%s recv=%s", m.Unique, unique))
+		} else {
+			perf.Unique = true
+		}
+		return errs
+	}
+	return append(errs, fmt.Errorf("Verify unique.txt missing in ZIP"))
+}
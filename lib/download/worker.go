@@ -0,0 +1,319 @@
+package download
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"sla/lib/duration"
+	"sla/lib/manifest"
+	"sla/lib/nntp"
+	"sla/lib/nzb"
+	"sla/lib/stream"
+	"sla/upload/yenc"
+	"time"
+)
+
+// Modes name the NNTP command sent per segment
+const MODE_ARTICLE = "article" // Full download (default)
+const MODE_BODY = "body"       // Download without headers
+const MODE_HEAD = "head"       // Only headers
+const MODE_STAT = "stat"       // Only availability
+
+const STATE_FOUND = "found"
+const STATE_MISSING = "missing" // 430 No such article
+const STATE_CORRUPT = "corrupt" // Incomplete or yEnc error
+
+type job struct {
+	Idx     int // Position in NZB
+	Segment nzb.Segment
+	Part    *manifest.Part // Expected content, nil if unknown
+}
+
+type result struct {
+	Idx      int
+	Bytes    uint64
+	State    string // STATE_FOUND, STATE_MISSING or STATE_CORRUPT
+	Status   int    // NNTP response code
+	Error    string // Why it's corrupt
+	Verified bool   // Matches manifest CRC
+	Timing   nntp.Timing
+	Begin    time.Time
+	End      time.Time
+}
+
+// Article smaller than posted
+type ByteCountError struct {
+	Expect int64
+	Got    uint64
+}
+
+func (e *ByteCountError) Error() string {
+	return fmt.Sprintf("ByteCount mismatch, expect>=%d recv=%d", e.Expect, e.Got)
+}
+
+// Article was found (2xx) but its headers or yEnc data
+// could not be decoded
+type CorruptError struct {
+	Err error
+}
+
+func (e *CorruptError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CorruptError) Unwrap() error {
+	return e.Err
+}
+
+// Article was received but the content is damaged
+func corrupt(e error) bool {
+	var content *CorruptError
+	var count *ByteCountError
+	return errors.As(e, &content) || errors.As(e, &count)
+}
+
+// Remembers the first read error of r, these come from the
+// connection and abort the run unlike decode errors.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errReader) Read(b []byte) (int, error) {
+	n, e := r.r.Read(b)
+	if e != nil && e != io.EOF && r.err == nil {
+		r.err = e
+	}
+	return n, e
+}
+
+// Download articles from jobs over one connection
+type worker struct {
+	Verbose  bool
+	SkipYenc bool
+	Mode     string      // MODE_ARTICLE, MODE_BODY, MODE_HEAD or MODE_STAT
+	Pipeline int         // Commands in flight, 1 waits for each response
+	Out      io.WriterAt // Reassemble decoded parts, nil to discard
+	Perf     nntp.ConnPerf
+
+	s    nntp.Server
+	conn *nntp.Client
+	busy time.Duration // Time spent on articles
+}
+
+func newWorker(c Config, s nntp.Server, id int, mode string, verbose bool, skipyenc bool) (*worker, error) {
+	name := fmt.Sprintf("%d", id)
+	conn := nntp.New(s.Address, name, verbose)
+	if e := conn.SetTLS(s.TLS); e != nil {
+		return nil, e
+	}
+	conn.DialTimeout = c.DialTimeout.Duration
+	conn.ReadTimeout = c.ReadTimeout.Duration
+	conn.WriteTimeout = c.WriteTimeout.Duration
+
+	return &worker{
+		Verbose:  verbose,
+		SkipYenc: skipyenc,
+		Mode:     mode,
+		Perf:     nntp.ConnPerf{Name: name},
+		s:        s,
+		conn:     conn,
+	}, nil
+}
+
+// Connect and authenticate while timing both
+func (w *worker) Connect(ctx context.Context) error {
+	perfBegin := time.Now()
+	if e := w.conn.InitContext(ctx); e != nil {
+		return e
+	}
+	perfInit := time.Now()
+	if e := w.conn.AuthContext(ctx, w.s.User, w.s.Pass); e != nil {
+		return e
+	}
+	perfAuth := time.Now()
+
+	// Switches mode-switching servers to reader mode
+	if _, e := w.conn.CapabilitiesContext(ctx); e != nil {
+		return e
+	}
+
+	w.Perf.Conn = duration.MilliSeconds(perfInit.Sub(perfBegin) - w.conn.TLSTime)
+	w.Perf.TLS = duration.MilliSeconds(w.conn.TLSTime)
+	w.Perf.Auth = duration.MilliSeconds(perfAuth.Sub(perfInit))
+	return nil
+}
+
+// Command in flight
+type flight struct {
+	job   job
+	begin time.Time
+}
+
+// Download until jobs is drained, keeps up to w.Pipeline
+// commands in flight.
+func (w *worker) Run(ctx context.Context, jobs <-chan job, results chan<- result) error {
+	p := w.conn.Pipeline(w.Pipeline)
+	var inflight []flight
+	var last time.Time // End of previous segment
+	drained := false
+	for {
+		for !drained && !p.Full() {
+			j, ok := <-jobs
+			if !ok {
+				drained = true
+				break
+			}
+			begin := time.Now()
+			if e := p.RetrieveContext(ctx, w.Mode, j.Segment.Msgid); e != nil {
+				return e
+			}
+			inflight = append(inflight, flight{job: j, begin: begin})
+		}
+		if len(inflight) == 0 {
+			break
+		}
+		if e := ctx.Err(); e != nil {
+			return e
+		}
+		f := inflight[0]
+		inflight = inflight[1:]
+		j, begin := f.job, f.begin
+
+		n, found, e := w.receive(ctx, p, j)
+		res := result{Idx: j.Idx, Bytes: n, State: STATE_FOUND, Status: w.conn.Status, Begin: begin}
+		if corrupt(e) {
+			// Received but damaged, continue with next
+			res.State = STATE_CORRUPT
+			res.Error = e.Error()
+			e = nil
+		} else if e == nil && !found {
+			res.State = STATE_MISSING
+		}
+		if e != nil {
+			return e
+		}
+		res.Verified = res.State == STATE_FOUND && j.Part != nil
+		res.End = time.Now()
+		res.Timing = w.conn.Timing
+		diff := res.End.Sub(begin)
+
+		if w.Verbose {
+			fmt.Println(fmt.Sprintf(
+				"C(%s) %s %s %s (%d bytes in %s with %f KB/s)",
+				w.Perf.Name, w.Mode, j.Segment.Msgid, res.State, n, diff.String(), float64(n/1024)/diff.Seconds(),
+			))
+		}
+
+		// Pipelined segments overlap, only count the time once
+		if last.After(begin) {
+			begin = last
+		}
+		w.busy += res.End.Sub(begin)
+		last = res.End
+		w.Perf.Arts++
+		w.Perf.Bytes += int64(n)
+		results <- res
+	}
+
+	if w.busy > 0 {
+		w.Perf.KBsec = float64(w.Perf.Bytes/1024) / w.busy.Seconds()
+	}
+	return nil
+}
+
+// Read the response to the oldest command of p, which requested j
+func (w *worker) receive(ctx context.Context, p *nntp.Pipeline, j job) (n uint64, found bool, e error) {
+	segment := j.Segment
+	found, e = p.FoundContext(ctx)
+	if !found || e != nil || w.Mode == MODE_STAT {
+		return 0, found, e
+	}
+
+	src := &errReader{r: w.conn.GetReaderContext(ctx)}
+	counter := stream.NewCountReader(src)
+	rawread := bufio.NewReader(counter)
+
+	var decErr error
+	if w.Mode == MODE_ARTICLE {
+		_, decErr = textproto.NewReader(rawread).ReadMIMEHeader()
+	}
+	// Decode while reading, only a line is kept in memory
+	if decErr == nil && w.Mode != MODE_HEAD && !w.SkipYenc {
+		dec := yenc.NewDecoder(rawread)
+		var out io.Writer = ioutil.Discard
+		if w.Out != nil {
+			out = &partWriter{w: w.Out, dec: dec}
+		}
+		_, decErr = io.Copy(out, dec)
+		if decErr == nil && j.Part != nil && j.Part.CRC32 != dec.CRC32() {
+			decErr = &yenc.CRCError{Expect: j.Part.CRC32, Got: dec.CRC32()}
+		}
+	}
+	// Rest of the article up to the terminator, also when it is
+	// damaged so the next response in the pipeline is in sync
+	io.Copy(ioutil.Discard, rawread)
+	if src.err != nil {
+		return 0, true, src.err
+	}
+	n = counter.ReadReset()
+	if int64(n) < segment.Bytes {
+		return n, true, &ByteCountError{Expect: segment.Bytes, Got: n}
+	}
+	if decErr != nil {
+		return n, true, &CorruptError{Err: decErr}
+	}
+	return n, true, nil
+}
+
+// Writes a decoded part at its offset in the reassembled file
+type partWriter struct {
+	w   io.WriterAt
+	dec *yenc.Decoder
+	off int64 // Written so far
+}
+
+func (p *partWriter) Write(b []byte) (int, error) {
+	n, e := p.w.WriteAt(b, p.dec.Header.Begin-1+p.off)
+	p.off += int64(n)
+	return n, e
+}
+
+func (w *worker) Close() error {
+	return w.conn.Close()
+}
+
+// Connect all workers and call fn for each in parallel,
+// the first error cancels the others and is returned.
+func pool(ctx context.Context, workers []*worker, fn func(ctx context.Context, w *worker) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(workers))
+	for _, w := range workers {
+		go func(w *worker) {
+			defer w.Close()
+			e := w.Connect(ctx)
+			if e == nil {
+				e = fn(ctx, w)
+			}
+			if e != nil {
+				// Stop others, first error is reported
+				cancel()
+			}
+			errs <- e
+		}(w)
+	}
+
+	var first error
+	for range workers {
+		if e := <-errs; e != nil && first == nil {
+			first = e
+		}
+	}
+	return first
+}
@@ -1,6 +1,7 @@
 package duration
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -8,5 +9,39 @@ import (
 func MilliSeconds(d time.Duration) float64 {
 	sec := d / time.Millisecond
 	nsec := d % time.Millisecond
-	return float64(sec) + float64(nsec)*1e-3
+	return float64(sec) + float64(nsec)*1e-6
+}
+
+// Milliseconds from begin until end, 0 if either was not reached
+func Between(begin time.Time, end time.Time) float64 {
+	if begin.IsZero() || end.IsZero() {
+		return 0
+	}
+	return MilliSeconds(end.Sub(begin))
+}
+
+// Duration reads human readable values like "30s" from config.json
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if e := json.Unmarshal(b, &s); e != nil {
+		return e
+	}
+	if s == "" {
+		d.Duration = 0
+		return nil
+	}
+	v, e := time.ParseDuration(s)
+	if e != nil {
+		return e
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
 }
@@ -1,15 +1,20 @@
 package duration
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 )
 
 func TestMilliSeconds(t *testing.T) {
 	df := map[string]float64{
-		"3s":   3000,
-		"10ms": 10,
-		"1m":   1000 * 60,
+		"3s":     3000,
+		"10ms":   10,
+		"1m":     1000 * 60,
+		"1.5ms":  1.5,
+		"8.25ms": 8.25,
+		"1500µs": 1.5,
+		"250µs":  0.25,
 	}
 	for str, expect := range df {
 		d, e := time.ParseDuration(str)
@@ -18,7 +23,37 @@ func TestMilliSeconds(t *testing.T) {
 		}
 		ms := MilliSeconds(d)
 		if ms != expect {
-			t.Fatalf("Input(%s) expect=%d but got=%d", str, expect, ms)
+			t.Fatalf("Input(%s) expect=%f but got=%f", str, expect, ms)
 		}
 	}
 }
+
+func TestBetween(t *testing.T) {
+	begin := time.Now()
+	if ms := Between(begin, begin.Add(1500*time.Microsecond)); ms != 1.5 {
+		t.Fatalf("Between expect=1.5 but got=%f", ms)
+	}
+	if ms := Between(begin, time.Time{}); ms != 0 {
+		t.Fatalf("Between unreached expect=0 but got=%f", ms)
+	}
+}
+
+func TestDurationJSON(t *testing.T) {
+	var c struct {
+		Read  Duration
+		Write Duration
+	}
+	if e := json.Unmarshal([]byte(`{"Read": "1m30s", "Write": ""}`), &c); e != nil {
+		t.Fatal(e)
+	}
+	if c.Read.Duration != 90*time.Second {
+		t.Fatalf("Read expect=1m30s but got=%s", c.Read)
+	}
+	if c.Write.Duration != 0 {
+		t.Fatalf("Write expect=0s but got=%s", c.Write)
+	}
+
+	if e := json.Unmarshal([]byte(`{"Read": "soon"}`), &c); e == nil {
+		t.Fatal("Invalid duration should fail")
+	}
+}
@@ -0,0 +1,68 @@
+// Sidecar of the NZB describing the uploaded ZIP, so
+// download can verify the reassembled bytes.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+type Manifest struct {
+	Name   string // Filename in yEnc header
+	Size   int64  // Bytes of whole file
+	SHA256 string // Hex checksum of whole file
+	Unique string // Content of unique.txt in ZIP
+	Parts  []Part
+}
+
+type Part struct {
+	Number int    // Segment number in NZB
+	Msgid  string //
+	Begin  int64  // Offset in file (1-based like =ypart)
+	Size   int64  // Decoded bytes
+	CRC32  uint32 // Checksum of decoded bytes
+}
+
+// Find part by NZB segment number
+func (m *Manifest) Part(number int) (Part, bool) {
+	if number > 0 && number <= len(m.Parts) && m.Parts[number-1].Number == number {
+		return m.Parts[number-1], true
+	}
+	for _, p := range m.Parts {
+		if p.Number == number {
+			return p, true
+		}
+	}
+	return Part{}, false
+}
+
+func Read(r io.Reader) (Manifest, error) {
+	var m Manifest
+	e := json.NewDecoder(r).Decode(&m)
+	return m, e
+}
+
+func (m *Manifest) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(m)
+}
+
+// Open manifest at path, ok is false if it does not exist
+func Open(path string) (m Manifest, ok bool, e error) {
+	f, e := os.Open(path)
+	if os.IsNotExist(e) {
+		return m, false, nil
+	}
+	if e != nil {
+		return m, false, e
+	}
+	defer f.Close()
+	m, e = Read(f)
+	if e != nil {
+		return m, false, fmt.Errorf("Invalid manifest %s: %s", path, e)
+	}
+	return m, true, nil
+}
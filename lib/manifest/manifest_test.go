@@ -0,0 +1,38 @@
+package manifest
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := Manifest{
+		Name:   "sla-2016-02-29.zip",
+		Size:   30,
+		SHA256: "abc",
+		Unique: "uniq",
+		Parts: []Part{
+			Part{Number: 1, Msgid: "a@test", Begin: 1, Size: 10, CRC32: 0x3274F3F7},
+			Part{Number: 2, Msgid: "b@test", Begin: 11, Size: 20, CRC32: 0x12AAC2CF},
+		},
+	}
+	buf := new(bytes.Buffer)
+	if e := in.Write(buf); e != nil {
+		t.Fatal(e)
+	}
+	out, e := Read(buf)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if out.Name != in.Name || out.SHA256 != in.SHA256 || out.Unique != in.Unique || len(out.Parts) != 2 {
+		t.Fatalf("Manifest mismatch. expect=%+v, found=%+v", in, out)
+	}
+
+	p, ok := out.Part(2)
+	if !ok || p != in.Parts[1] {
+		t.Fatalf("Part mismatch. expect=%+v, found=%+v", in.Parts[1], p)
+	}
+	if _, ok := out.Part(3); ok {
+		t.Fatal("Part 3 should not exist")
+	}
+}
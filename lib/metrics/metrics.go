@@ -0,0 +1,259 @@
+// Minimal Prometheus text format (0.0.4) exporter so
+// SLA results can be scraped or written for the
+// node_exporter textfile collector.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Latency buckets in seconds
+var LATENCY_BUCKETS = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+type Labels map[string]string
+
+// Canonical form used as series key and in output
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+`="`+escape(l[k])+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func escape(v string) string {
+	v = strings.Replace(v, `\`, `\\`, -1)
+	v = strings.Replace(v, `"`, `\"`, -1)
+	return strings.Replace(v, "\n", `\n`, -1)
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+type Registry struct {
+	mu      sync.Mutex
+	names   []string
+	metrics map[string]metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+func (r *Registry) add(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[name]; ok {
+		panic("Duplicate metric: " + name)
+	}
+	r.names = append(r.names, name)
+	r.metrics[name] = m
+}
+
+// Write all metrics in Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	buf := new(bytes.Buffer)
+	r.mu.Lock()
+	for _, name := range r.names {
+		r.metrics[name].write(buf)
+	}
+	r.mu.Unlock()
+	return buf.WriteTo(w)
+}
+
+// Serve /metrics
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.WriteTo(w)
+}
+
+// Atomic write for the textfile collector, it must never
+// see a partially written file.
+func (r *Registry) WriteFile(path string) error {
+	tmp, e := ioutil.TempFile(filepath.Dir(path), ".sla-metrics-")
+	if e != nil {
+		return e
+	}
+	if _, e := r.WriteTo(tmp); e != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return e
+	}
+	if e := tmp.Close(); e != nil {
+		os.Remove(tmp.Name())
+		return e
+	}
+	if e := os.Chmod(tmp.Name(), 0644); e != nil {
+		os.Remove(tmp.Name())
+		return e
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func header(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+func value(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func series(name string, labels string, extra string) string {
+	if extra != "" {
+		if labels != "" {
+			labels += ","
+		}
+		labels += extra
+	}
+	if labels == "" {
+		return name
+	}
+	return name + "{" + labels + "}"
+}
+
+// Value per label set, counter only goes up
+type valueMetric struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	typ    string
+	values map[string]float64
+}
+
+func (m *valueMetric) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	header(w, m.name, m.help, m.typ)
+	keys := make([]string, 0, len(m.values))
+	for k := range m.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s %s\n", series(m.name, k, ""), value(m.values[k]))
+	}
+}
+
+type Counter struct {
+	valueMetric
+}
+
+func (r *Registry) Counter(name, help string) *Counter {
+	c := &Counter{valueMetric{name: name, help: help, typ: "counter", values: make(map[string]float64)}}
+	r.add(name, c)
+	return c
+}
+
+func (c *Counter) Add(l Labels, v float64) {
+	if v < 0 {
+		panic("Counter cannot decrease")
+	}
+	c.mu.Lock()
+	c.values[l.String()] += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) Inc(l Labels) {
+	c.Add(l, 1)
+}
+
+type Gauge struct {
+	valueMetric
+}
+
+func (r *Registry) Gauge(name, help string) *Gauge {
+	g := &Gauge{valueMetric{name: name, help: help, typ: "gauge", values: make(map[string]float64)}}
+	r.add(name, g)
+	return g
+}
+
+func (g *Gauge) Set(l Labels, v float64) {
+	g.mu.Lock()
+	g.values[l.String()] = v
+	g.mu.Unlock()
+}
+
+type histSeries struct {
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+type Histogram struct {
+	mu      sync.Mutex
+	name    string
+	help    string
+	buckets []float64
+	series  map[string]*histSeries
+}
+
+func (r *Registry) Histogram(name, help string, buckets []float64) *Histogram {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &Histogram{name: name, help: help, buckets: b, series: make(map[string]*histSeries)}
+	r.add(name, h)
+	return h
+}
+
+func (h *Histogram) Observe(l Labels, v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := l.String()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, le := range h.buckets {
+		if v <= le {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	header(w, h.name, h.help, "histogram")
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := h.series[k]
+		var cum uint64
+		for i, le := range h.buckets {
+			cum += s.counts[i]
+			fmt.Fprintf(w, "%s %d\n", series(h.name+"_bucket", k, `le="`+value(le)+`"`), cum)
+		}
+		fmt.Fprintf(w, "%s %d\n", series(h.name+"_bucket", k, `le="+Inf"`), s.count)
+		fmt.Fprintf(w, "%s %s\n", series(h.name+"_sum", k, ""), value(s.sum))
+		fmt.Fprintf(w, "%s %d\n", series(h.name+"_count", k, ""), s.count)
+	}
+}
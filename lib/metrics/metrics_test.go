@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("sla_connect_seconds", "Connect time.", []float64{0.5, 0.1})
+	c := r.Counter("sla_errors_total", "Errors.")
+	g := r.Gauge("sla_throughput_bytes_per_second", "Throughput.")
+
+	l := Labels{"server": `news "1"`, "probe": "download"}
+	h.Observe(l, 0.05)
+	h.Observe(l, 0.2)
+	h.Observe(l, 3)
+	c.Inc(l)
+	c.Add(l, 2)
+	g.Set(Labels{}, 1024)
+
+	buf := new(bytes.Buffer)
+	if _, e := r.WriteTo(buf); e != nil {
+		t.Fatal(e)
+	}
+	expect := `# HELP sla_connect_seconds Connect time.
+# TYPE sla_connect_seconds histogram
+sla_connect_seconds_bucket{probe="download",server="news \"1\"",le="0.1"} 1
+sla_connect_seconds_bucket{probe="download",server="news \"1\"",le="0.5"} 2
+sla_connect_seconds_bucket{probe="download",server="news \"1\"",le="+Inf"} 3
+sla_connect_seconds_sum{probe="download",server="news \"1\""} 3.25
+sla_connect_seconds_count{probe="download",server="news \"1\""} 3
+# HELP sla_errors_total Errors.
+# TYPE sla_errors_total counter
+sla_errors_total{probe="download",server="news \"1\""} 3
+# HELP sla_throughput_bytes_per_second Throughput.
+# TYPE sla_throughput_bytes_per_second gauge
+sla_throughput_bytes_per_second 1024
+`
+	if buf.String() != expect {
+		t.Fatalf("Output mismatch\nGEN=\n%s\nHARDCODED=\n%s", buf.String(), expect)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Body.String() != expect {
+		t.Fatal("ServeHTTP output mismatch")
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("Invalid Content-Type: %s", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, e := ioutil.TempDir("", "metrics")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	r := NewRegistry()
+	r.Gauge("sla_up", "Up.").Set(Labels{}, 1)
+	path := filepath.Join(dir, "sla.prom")
+	if e := r.WriteFile(path); e != nil {
+		t.Fatal(e)
+	}
+	b, e := ioutil.ReadFile(path)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !strings.Contains(string(b), "sla_up 1\n") {
+		t.Fatalf("Missing value in %s", b)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("Tempfile left behind, found %d files", len(files))
+	}
+}
@@ -0,0 +1,48 @@
+package metrics
+
+// Throughput buckets in bytes/sec (64KB/s to 1GB/s)
+var THROUGHPUT_BUCKETS = []float64{1 << 16, 1 << 18, 1 << 20, 1 << 22, 1 << 24, 1 << 26, 1 << 28, 1 << 30}
+
+// Propagation buckets in seconds
+var PROPAGATION_BUCKETS = []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300, 900}
+
+// Metrics shared by upload and download, labelled
+// with server and probe.
+type SLA struct {
+	Connect    *Histogram // TCP connect
+	TLS        *Histogram // TLS handshake
+	Auth       *Histogram
+	Article    *Histogram // Post or download of one article
+	Throughput *Histogram // Bytes/sec per article
+	Speed      *Gauge     // Bytes/sec all conns combined
+	Articles   *Counter   // By state
+	Completion *Gauge     // Percent of segments found
+	Propagate  *Histogram // Delay until a post appears on a peer
+	Errors     *Counter
+	Timestamp  *Gauge // Unix time of last run
+}
+
+func NewSLA(r *Registry) *SLA {
+	return &SLA{
+		Connect:    r.Histogram("sla_connect_seconds", "Time to connect to the NNTP server.", LATENCY_BUCKETS),
+		TLS:        r.Histogram("sla_tls_handshake_seconds", "Time of the TLS handshake.", LATENCY_BUCKETS),
+		Auth:       r.Histogram("sla_auth_seconds", "Time to authenticate.", LATENCY_BUCKETS),
+		Article:    r.Histogram("sla_article_seconds", "Time to transfer one article.", LATENCY_BUCKETS),
+		Throughput: r.Histogram("sla_article_throughput_bytes_per_second", "Throughput of one article.", THROUGHPUT_BUCKETS),
+		Speed:      r.Gauge("sla_throughput_bytes_per_second", "Throughput of all connections combined."),
+		Articles:   r.Counter("sla_articles_total", "Articles by state."),
+		Completion: r.Gauge("sla_completion_percent", "Segments found in percent."),
+		Propagate:  r.Histogram("sla_propagation_seconds", "Time until a posted article appears on a peer.", PROPAGATION_BUCKETS),
+		Errors:     r.Counter("sla_errors_total", "Errors reported by the probe."),
+		Timestamp:  r.Gauge("sla_last_run_timestamp_seconds", "Unix time of the last run."),
+	}
+}
+
+// Labels for one probe run, more pairs are added in order
+func Label(server string, probe string, pairs ...string) Labels {
+	l := Labels{"server": server, "probe": probe}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		l[pairs[i]] = pairs[i+1]
+	}
+	return l
+}
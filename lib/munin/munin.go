@@ -0,0 +1,76 @@
+// Munin plugin output with multigraph support, see
+// http://guide.munin-monitoring.org/en/latest/plugin/multigraphing.html
+package munin
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+var invalid = regexp.MustCompile("[^a-zA-Z0-9_]")
+
+// Field name munin accepts
+func Clean(name string) string {
+	name = invalid.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+type Field struct {
+	Name  string // Cleaned on output
+	Label string
+	Value float64
+}
+
+type Graph struct {
+	Name     string // Multigraph name
+	Title    string
+	VLabel   string
+	Category string
+	Info     string
+	Fields   []Field
+}
+
+func (g *Graph) Add(name string, label string, v float64) {
+	g.Fields = append(g.Fields, Field{Name: name, Label: label, Value: v})
+}
+
+// Output of `plugin config`
+func Config(w io.Writer, graphs []Graph) error {
+	for _, g := range graphs {
+		if _, e := fmt.Fprintf(w, "multigraph %s\ngraph_title %s\ngraph_vlabel %s\ngraph_category %s\ngraph_scale no\n", Clean(g.Name), g.Title, g.VLabel, g.Category); e != nil {
+			return e
+		}
+		if g.Info != "" {
+			if _, e := fmt.Fprintf(w, "graph_info %s\n", g.Info); e != nil {
+				return e
+			}
+		}
+		for _, f := range g.Fields {
+			name := Clean(f.Name)
+			if _, e := fmt.Fprintf(w, "%s.label %s\n%s.min 0\n", name, f.Label, name); e != nil {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// Output of `plugin`
+func Values(w io.Writer, graphs []Graph) error {
+	for _, g := range graphs {
+		if _, e := fmt.Fprintf(w, "multigraph %s\n", Clean(g.Name)); e != nil {
+			return e
+		}
+		for _, f := range g.Fields {
+			if _, e := fmt.Fprintf(w, "%s.value %s\n", Clean(f.Name), strconv.FormatFloat(f.Value, 'f', 2, 64)); e != nil {
+				return e
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package munin
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	tests := map[string]string{
+		"frontend":         "frontend",
+		"news.usenet.farm": "news_usenet_farm",
+		"127.0.0.1:119":    "_127_0_0_1_119",
+		"":                 "_",
+	}
+	for in, expect := range tests {
+		if out := Clean(in); out != expect {
+			t.Errorf("Clean(%q) mismatch. expect=%s, found=%s", in, expect, out)
+		}
+	}
+}
+
+func TestOutput(t *testing.T) {
+	g := Graph{Name: "sla_download_speed", Title: "SLA download speed", VLabel: "KB/s", Category: "sla"}
+	g.Add("news.usenet.farm", "news.usenet.farm", 1234.567)
+	graphs := []Graph{g}
+
+	buf := new(bytes.Buffer)
+	if e := Config(buf, graphs); e != nil {
+		t.Fatal(e)
+	}
+	expect := `multigraph sla_download_speed
+graph_title SLA download speed
+graph_vlabel KB/s
+graph_category sla
+graph_scale no
+news_usenet_farm.label news.usenet.farm
+news_usenet_farm.min 0
+`
+	if buf.String() != expect {
+		t.Errorf("Config mismatch.\nGEN=\n%s\nHARDCODED=\n%s", buf.String(), expect)
+	}
+
+	buf.Reset()
+	if e := Values(buf, graphs); e != nil {
+		t.Fatal(e)
+	}
+	expect = "multigraph sla_download_speed\nnews_usenet_farm.value 1234.57\n"
+	if buf.String() != expect {
+		t.Errorf("Values mismatch.\nGEN=\n%s\nHARDCODED=\n%s", buf.String(), expect)
+	}
+}
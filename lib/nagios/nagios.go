@@ -0,0 +1,133 @@
+// Nagios/Icinga plugin output, see
+// https://nagios-plugins.org/doc/guidelines.html
+package nagios
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const OK = 0
+const WARNING = 1
+const CRITICAL = 2
+const UNKNOWN = 3
+
+var labels = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// Warning and critical threshold, -1 disables
+type Threshold struct {
+	Warn float64
+	Crit float64
+}
+
+// Register -<name>-warn and -<name>-crit on fs
+func (t *Threshold) Flags(fs *flag.FlagSet, name string, unit string, warn float64, crit float64) {
+	fs.Float64Var(&t.Warn, name+"-warn", warn, "Warning threshold for "+name+" in "+unit+" (-1=off)")
+	fs.Float64Var(&t.Crit, name+"-crit", crit, "Critical threshold for "+name+" in "+unit+" (-1=off)")
+}
+
+func (t Threshold) perf(v float64) string {
+	if v < 0 {
+		return ""
+	}
+	return value(v)
+}
+
+// Rounded to 2 decimals
+func value(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+type Check struct {
+	Name string // Service in status line
+
+	status   int
+	messages []string
+	perfdata []string
+}
+
+func New(name string) *Check {
+	return &Check{Name: name}
+}
+
+// Raise status to at least s
+func (c *Check) raise(s int, msg string) {
+	// UNKNOWN only wins over OK
+	if c.status == OK || (s != UNKNOWN && (s > c.status || c.status == UNKNOWN)) {
+		c.status = s
+	}
+	if msg != "" {
+		c.messages = append(c.messages, msg)
+	}
+}
+
+func (c *Check) Critical(msg string) {
+	c.raise(CRITICAL, msg)
+}
+
+func (c *Check) Warning(msg string) {
+	c.raise(WARNING, msg)
+}
+
+func (c *Check) Unknown(msg string) {
+	c.raise(UNKNOWN, msg)
+}
+
+// Add msg without changing the status
+func (c *Check) Info(msg string) {
+	c.messages = append(c.messages, msg)
+}
+
+// Add perfdata, label is quoted and uom appended to v
+func (c *Check) Perf(label string, v float64, uom string, t Threshold) {
+	c.perfdata = append(c.perfdata, fmt.Sprintf(
+		"'%s'=%s%s;%s;%s;0;",
+		strings.Replace(label, "'", "''", -1), value(v), uom, t.perf(t.Warn), t.perf(t.Crit),
+	))
+}
+
+// Value where lower is worse (speed, completion)
+func (c *Check) Min(label string, v float64, uom string, t Threshold) {
+	c.Perf(label, v, uom, t)
+	if t.Crit >= 0 && v < t.Crit {
+		c.Critical(fmt.Sprintf("%s %s%s < %s%s", label, value(v), uom, value(t.Crit), uom))
+	} else if t.Warn >= 0 && v < t.Warn {
+		c.Warning(fmt.Sprintf("%s %s%s < %s%s", label, value(v), uom, value(t.Warn), uom))
+	}
+}
+
+// Value where higher is worse (latency, errors)
+func (c *Check) Max(label string, v float64, uom string, t Threshold) {
+	c.Perf(label, v, uom, t)
+	if t.Crit >= 0 && v > t.Crit {
+		c.Critical(fmt.Sprintf("%s %s%s > %s%s", label, value(v), uom, value(t.Crit), uom))
+	} else if t.Warn >= 0 && v > t.Warn {
+		c.Warning(fmt.Sprintf("%s %s%s > %s%s", label, value(v), uom, value(t.Warn), uom))
+	}
+}
+
+func (c *Check) Status() int {
+	return c.status
+}
+
+// Status line with perfdata
+func (c *Check) String() string {
+	line := labels[c.status] + " - " + c.Name
+	if len(c.messages) > 0 {
+		line += ": " + strings.Join(c.messages, ", ")
+	}
+	if len(c.perfdata) > 0 {
+		line += " | " + strings.Join(c.perfdata, " ")
+	}
+	return line
+}
+
+// Print status line to w and return exit code
+func (c *Check) Write(w io.Writer) int {
+	fmt.Fprintln(w, c.String())
+	return c.status
+}
@@ -0,0 +1,69 @@
+package nagios
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOK(t *testing.T) {
+	c := New("SLA/DOWNLOAD")
+	c.Min("speed", 250.504, "KB", Threshold{Warn: 200, Crit: 100})
+	c.Max("errors", 0, "", Threshold{Warn: -1, Crit: 0})
+
+	buf := new(bytes.Buffer)
+	if code := c.Write(buf); code != OK {
+		t.Errorf("Exit code mismatch. expect=%d, found=%d", OK, code)
+	}
+	expect := "OK - SLA/DOWNLOAD | 'speed'=250.5KB;200;100;0; 'errors'=0;;0;0;\n"
+	if buf.String() != expect {
+		t.Errorf("Output mismatch.\nexpect=%s\nfound=%s", expect, buf.String())
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	tests := []struct {
+		Min    bool
+		Value  float64
+		Status int
+	}{
+		{true, 150, WARNING},
+		{true, 50, CRITICAL},
+		{true, 100, WARNING},
+		{false, 150, OK},
+		{false, 250, WARNING},
+		{false, 600, CRITICAL},
+	}
+	for _, test := range tests {
+		c := New("test")
+		if test.Min {
+			c.Min("v", test.Value, "", Threshold{Warn: 200, Crit: 100})
+		} else {
+			c.Max("v", test.Value, "", Threshold{Warn: 200, Crit: 500})
+		}
+		if c.Status() != test.Status {
+			t.Errorf("Status mismatch for %+v. found=%d (%s)", test, c.Status(), c)
+		}
+	}
+}
+
+func TestWorstWins(t *testing.T) {
+	c := New("test")
+	c.Unknown("no data")
+	c.Warning("slow")
+	if c.Status() != WARNING {
+		t.Errorf("Warning should win over unknown, found=%d", c.Status())
+	}
+	c.Critical("down")
+	c.Warning("slow")
+	if c.Status() != CRITICAL {
+		t.Errorf("Critical should win, found=%d", c.Status())
+	}
+	c.Unknown("no data")
+	if c.Status() != CRITICAL {
+		t.Errorf("Unknown should not lower critical, found=%d", c.Status())
+	}
+	expect := "CRITICAL - test: no data, slow, down, slow, no data"
+	if c.String() != expect {
+		t.Errorf("Output mismatch.\nexpect=%s\nfound=%s", expect, c.String())
+	}
+}
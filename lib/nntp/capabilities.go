@@ -0,0 +1,152 @@
+package nntp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+const CAP_READER = "READER"
+const CAP_POST = "POST"
+const CAP_STREAMING = "STREAMING"
+const CAP_IHAVE = "IHAVE"
+const CAP_STARTTLS = "STARTTLS"
+const CAP_AUTHINFO = "AUTHINFO"
+const CAP_COMPRESS = "COMPRESS"
+const CAP_MODE_READER = "MODE-READER"
+
+// Capability labels with their arguments (RFC 3977 5.2),
+// e.g. AUTHINFO: [USER SASL]
+type Capabilities map[string][]string
+
+// Server advertised label
+func (c Capabilities) Has(label string) bool {
+	_, ok := c[strings.ToUpper(label)]
+	return ok
+}
+
+// Labels of expect that are not advertised
+func (c Capabilities) Missing(expect []string) []string {
+	missing := []string{}
+	for _, label := range expect {
+		if !c.Has(label) {
+			missing = append(missing, label)
+		}
+	}
+	return missing
+}
+
+// Sorted labels
+func (c Capabilities) Labels() []string {
+	labels := make([]string, 0, len(c))
+	for label := range c {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Expected capabilities the server does not advertise
+type CapabilityError struct {
+	Missing []string
+}
+
+func (e *CapabilityError) Error() string {
+	return "Missing capabilities: " + strings.Join(e.Missing, ", ")
+}
+
+// CapabilityError if caps lacks any of expect
+func (c Capabilities) Expect(expect []string) error {
+	if missing := c.Missing(expect); len(missing) > 0 {
+		return &CapabilityError{Missing: missing}
+	}
+	return nil
+}
+
+func parseCapabilities(lines []string) Capabilities {
+	caps := Capabilities{}
+	for _, line := range lines {
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		caps[strings.ToUpper(args[0])] = args[1:]
+	}
+	return caps
+}
+
+func (c *Client) Capabilities() (Capabilities, error) {
+	return c.CapabilitiesContext(context.Background())
+}
+
+// Ask CAPABILITIES and switch mode-switching servers to reader
+// mode, servers without CAPABILITIES get MODE READER and the
+// capabilities it implies. The result is kept in c.Caps.
+func (c *Client) CapabilitiesContext(ctx context.Context) (Capabilities, error) {
+	caps, e := c.capabilities(ctx)
+	var res *ResponseError
+	if errors.As(e, &res) && !res.Temporary() {
+		// 500 unknown command (RFC 977 server)
+		caps, e = c.modeReader(ctx, Capabilities{})
+		if errors.As(e, &res) && !res.Temporary() {
+			// Knows neither, nothing to report
+			caps, e = Capabilities{}, nil
+		}
+	} else if e == nil && caps.Has(CAP_MODE_READER) && !caps.Has(CAP_READER) {
+		if _, e = c.modeReader(ctx, caps); e == nil {
+			caps, e = c.capabilities(ctx)
+		}
+	}
+	if e != nil {
+		return nil, e
+	}
+	c.Caps = caps
+	return caps, nil
+}
+
+// Ask CAPABILITIES without switching to reader mode so transit
+// servers keep IHAVE and streaming, servers without CAPABILITIES
+// report nothing. The result is kept in c.Caps.
+func (c *Client) FeedCapabilitiesContext(ctx context.Context) (Capabilities, error) {
+	caps, e := c.capabilities(ctx)
+	var res *ResponseError
+	if errors.As(e, &res) && !res.Temporary() {
+		caps, e = Capabilities{}, nil
+	}
+	if e != nil {
+		return nil, e
+	}
+	c.Caps = caps
+	return caps, nil
+}
+
+func (c *Client) capabilities(ctx context.Context) (Capabilities, error) {
+	if _, e := c.SendContext(ctx, "CAPABILITIES", []Expect{Expect{"101 ", false}}); e != nil {
+		return nil, e
+	}
+	var lines []string
+	r := bufio.NewScanner(c.GetReaderContext(ctx))
+	for r.Scan() {
+		lines = append(lines, r.Text())
+	}
+	if e := r.Err(); e != nil {
+		return nil, e
+	}
+	return parseCapabilities(lines), nil
+}
+
+// MODE READER, 200 allows posting and 201 does not
+func (c *Client) modeReader(ctx context.Context, caps Capabilities) (Capabilities, error) {
+	if _, e := c.SendContext(ctx, "MODE READER", []Expect{
+		Expect{"200 ", false}, Expect{"201 ", false},
+	}); e != nil {
+		return nil, e
+	}
+	caps[CAP_READER] = []string{}
+	if c.Status == 200 {
+		caps[CAP_POST] = []string{}
+	}
+	return caps, nil
+}
@@ -0,0 +1,136 @@
+package nntp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Server answering each command with the next response of script,
+// article blocks (after 335/340 and TAKETHIS) are answered by "."
+func scripted(t *testing.T, script map[string][]string) *Client {
+	l, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	go func() {
+		defer l.Close()
+		conn, e := l.Accept()
+		if e != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		conn.Write([]byte("200 welcome\r\n"))
+		block := false
+		pending := "" // Response of TAKETHIS
+		for {
+			line, e := r.ReadString('\n')
+			if e != nil {
+				return
+			}
+			cmd := strings.TrimSpace(line)
+			if block && line != ".\r\n" {
+				continue
+			}
+			res := "500 unknown command\r\n"
+			if next := script[cmd]; len(next) > 0 {
+				res, script[cmd] = next[0], next[1:]
+			}
+			if block && pending != "" {
+				res, pending = pending, ""
+			}
+			if !block && strings.HasPrefix(cmd, "TAKETHIS ") {
+				// Answered after the article
+				block, pending = true, res
+				continue
+			}
+			block = strings.HasPrefix(res, "335 ") || strings.HasPrefix(res, "340 ")
+			conn.Write([]byte(res))
+		}
+	}()
+
+	c := New(l.Addr().String(), "test", false)
+	if e := c.InitContext(context.Background()); e != nil {
+		t.Fatal(e)
+	}
+	return c
+}
+
+func TestCapabilities(t *testing.T) {
+	c := scripted(t, map[string][]string{
+		"CAPABILITIES": {"101 list\r\nVERSION 2\r\nREADER\r\nPOST\r\nAUTHINFO USER SASL\r\ncompress DEFLATE\r\n.\r\n"},
+	})
+	defer c.Close()
+
+	caps, e := c.Capabilities()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(caps.Labels(), []string{"AUTHINFO", "COMPRESS", "POST", "READER", "VERSION"}) {
+		t.Fatalf("Labels got=%v", caps.Labels())
+	}
+	if !reflect.DeepEqual(caps[CAP_AUTHINFO], []string{"USER", "SASL"}) {
+		t.Fatalf("AUTHINFO args got=%v", caps[CAP_AUTHINFO])
+	}
+	if !c.Caps.Has("compress") {
+		t.Fatal("Caps not kept on client")
+	}
+
+	if e := caps.Expect([]string{"reader", "post"}); e != nil {
+		t.Fatal(e)
+	}
+	var capErr *CapabilityError
+	if e := caps.Expect([]string{"READER", "STREAMING", "STARTTLS"}); !errors.As(e, &capErr) {
+		t.Fatalf("Expect CapabilityError but got=%v", e)
+	}
+	if !reflect.DeepEqual(capErr.Missing, []string{"STREAMING", "STARTTLS"}) {
+		t.Fatalf("Missing got=%v", capErr.Missing)
+	}
+	if f := NewFailure(capErr); f.Class != FAIL_CAPABILITY {
+		t.Fatalf("Class expect=%s but got=%s", FAIL_CAPABILITY, f.Class)
+	}
+}
+
+func TestCapabilitiesModeSwitch(t *testing.T) {
+	c := scripted(t, map[string][]string{
+		"CAPABILITIES": {
+			"101 list\r\nVERSION 2\r\nMODE-READER\r\nIHAVE\r\n.\r\n",
+			"101 list\r\nVERSION 2\r\nREADER\r\n.\r\n",
+		},
+		"MODE READER": {"201 reader, no posting\r\n"},
+	})
+	defer c.Close()
+
+	caps, e := c.Capabilities()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(caps.Labels(), []string{"READER", "VERSION"}) {
+		t.Fatalf("Capabilities after MODE READER got=%v", caps.Labels())
+	}
+}
+
+func TestCapabilitiesFallback(t *testing.T) {
+	for code, expect := range map[string][]string{
+		"200": {"POST", "READER"},
+		"201": {"READER"},
+		"502": {}, // Knows neither
+	} {
+		c := scripted(t, map[string][]string{
+			"MODE READER": {code + " mode reader\r\n"},
+		})
+		caps, e := c.Capabilities()
+		c.Close()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !reflect.DeepEqual(caps.Labels(), expect) {
+			t.Fatalf("MODE READER %s expect=%v but got=%v", code, expect, caps.Labels())
+		}
+	}
+}
@@ -2,17 +2,19 @@ package nntp
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
 	"errors"
-	"fmt"
 	"log"
 	"net"
 	"os"
 	"strings"
+	"time"
 )
 
-const EOF = "\r\n"      // End of File
-const EOM = "\r\n.\r\n" // End of multiline data-block
-var ERR_RANGE = errors.New("NNTP StatusCode > 2xx")
+const EOF = "\r\n"                                  // End of File
+const EOM = "\r\n.\r\n"                             // End of multiline data-block
+var ERR_RANGE = errors.New("NNTP StatusCode > 2xx") // Matches every ResponseError
 
 type Client struct {
 	Name    string
@@ -25,36 +27,82 @@ type Client struct {
 	r    *bufio.Reader
 	w    *bufio.Writer
 
+	tls     *tls.Config
+	tlsMode string
+	TLSTime time.Duration // Duration of TLS handshake
+
+	DialTimeout  time.Duration // 0 = no timeout
+	ReadTimeout  time.Duration // Max wait per read, 0 = no timeout
+	WriteTimeout time.Duration // Max wait per write, 0 = no timeout
+
+	Caps     Capabilities // Filled by Capabilities
+	Status   int          // Code of last response
+	Response Response     // Last response
+	Timing   Timing       // Of last command
+	cmd      string       // Last command, redacted
 	BytesIn  int64
 	BytesOut int64
 }
 
 func (c *Client) Init() error {
-	conn, e := net.Dial("tcp", c.listen)
+	return c.InitContext(context.Background())
+}
+
+// Connect and read welcome, aborts when ctx is done.
+func (c *Client) InitContext(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: c.DialTimeout}
+	raw, e := dialer.DialContext(ctx, "tcp", c.listen)
 	if e != nil {
 		return e
 	}
-	c.conn = conn
-	c.r = bufio.NewReader(conn)
-	c.w = bufio.NewWriter(conn)
+	var conn net.Conn = &deadlineConn{Conn: raw, read: c.ReadTimeout, write: c.WriteTimeout}
+	if c.tlsMode == TLS_IMPLICIT {
+		tc, e := c.handshake(ctx, conn)
+		if e != nil {
+			conn.Close()
+			return e
+		}
+		conn = tc
+	}
+	c.setConn(conn)
 
 	// Welcome
-	l, e := c.Read()
+	l, e := c.ReadContext(ctx)
 	if e != nil {
 		return e
 	}
 	if !strings.HasPrefix(l, "20") {
 		// 2xx - Command ok
 		// x0x - Connection, setup, and miscellaneous messages
+		if res, e := ParseResponse(l); e == nil && res.Code >= 400 {
+			// 400 service unavailable, 502 access denied
+			return &ResponseError{res}
+		}
 		return errors.New("Invalid welcome: " + l)
 	}
+
+	if c.tlsMode == TLS_STARTTLS {
+		return c.startTLS(ctx)
+	}
 	return nil
 }
 
+func (c *Client) setConn(conn net.Conn) {
+	c.conn = conn
+	c.r = bufio.NewReader(conn)
+	c.w = bufio.NewWriter(conn)
+}
+
 func (c *Client) Read() (string, error) {
+	return c.ReadContext(context.Background())
+}
+
+func (c *Client) ReadContext(ctx context.Context) (string, error) {
+	stop := c.watch(ctx)
 	txt, e := c.r.ReadString(EOF[1])
+	stop()
 	if e != nil {
-		return "", e
+		return "", ctxErr(ctx, e)
 	}
 	if strings.HasSuffix(txt, EOF) {
 		txt = txt[:len(txt)-2] // Strip EOF
@@ -69,10 +117,24 @@ func (c *Client) Read() (string, error) {
 
 // Check next line for expected prefixes
 func (c *Client) Expect(prefixes []Expect) (string, error) {
-	l, e := c.Read()
+	return c.ExpectContext(context.Background(), prefixes)
+}
+
+func (c *Client) ExpectContext(ctx context.Context, prefixes []Expect) (string, error) {
+	l, e := c.ReadContext(ctx)
 	if e != nil {
 		return "", e
 	}
+	if c.Timing.Status.IsZero() {
+		c.Timing.Status = time.Now()
+	}
+	res, e := ParseResponse(l)
+	c.Status = res.Code
+	if e != nil {
+		return "", &ProtocolError{Line: l, Cmd: c.cmd, Expect: prefixes}
+	}
+	res.Cmd = c.cmd
+	c.Response = res
 
 	ok := false
 	errRange := false
@@ -85,38 +147,79 @@ func (c *Client) Expect(prefixes []Expect) (string, error) {
 			break
 		}
 	}
-	if !ok {
-		return "", fmt.Errorf("Protocol error. Received=%s (Expected=%+v)", l, prefixes)
+	if !ok && res.Code < 400 {
+		return "", &ProtocolError{Line: l, Cmd: c.cmd, Expect: prefixes}
 	}
-	if errRange {
-		return l, ERR_RANGE
+	if !ok || errRange {
+		// Also matches ERR_RANGE with errors.Is
+		return l, &ResponseError{res}
 	}
 	return l, nil
 }
 
 // Send cmd and expect response to begin with prefix
 func (c *Client) Send(cmd string, prefixes []Expect) (string, error) {
-	c.log("C(%s) >> %s", c.Name, cmd)
-	if _, e := c.w.WriteString(cmd + EOF); e != nil {
-		return "", e
-	}
-	if e := c.w.Flush(); e != nil {
+	return c.SendContext(context.Background(), cmd, prefixes)
+}
+
+func (c *Client) SendContext(ctx context.Context, cmd string, prefixes []Expect) (string, error) {
+	if e := c.write(ctx, cmd); e != nil {
 		return "", e
 	}
-
-	l, e := c.Expect(prefixes)
+	l, e := c.ExpectContext(ctx, prefixes)
 	if e != nil {
 		return l, e
 	}
 	return l, nil
 }
 
+// Write cmd without reading the response
+func (c *Client) write(ctx context.Context, cmd string) error {
+	c.log("C(%s) >> %s", c.Name, cmd)
+	stop := c.watch(ctx)
+	_, e := c.w.WriteString(cmd + EOF)
+	if e == nil {
+		e = c.w.Flush()
+	}
+	stop()
+	if e != nil {
+		return ctxErr(ctx, e)
+	}
+	c.Timing = Timing{Sent: time.Now()}
+	c.cmd = redact(cmd)
+	return nil
+}
+
 func (c *Client) GetWriter() *bufio.Writer {
 	return c.w
 }
 
 func (c *Client) GetReader() *DotReader {
-	return NewDotReader(c.r, false)
+	return c.GetReaderContext(context.Background())
+}
+
+// Multi-line block of the last command, stamps Timing. The
+// connection is closed when ctx is done before io.EOF.
+func (c *Client) GetReaderContext(ctx context.Context) *DotReader {
+	d := NewDotReader(c.r)
+	d.timing = &c.Timing
+	d.ctx = ctx
+	d.stop = c.watch(ctx)
+	return d
+}
+
+func (c *Client) GetDotWriter() *DotWriter {
+	return c.GetDotWriterContext(context.Background())
+}
+
+// Article for POST, Close it before PostClose, stamps Timing.
+// The connection is closed when ctx is done before Close.
+func (c *Client) GetDotWriterContext(ctx context.Context) *DotWriter {
+	d := NewDotWriter(c.w)
+	d.timing = &c.Timing
+	d.ctx = ctx
+	d.stop = c.watch(ctx)
+	return d
 }
 
 func New(listen string, name string, verbose bool) *Client {
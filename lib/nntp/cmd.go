@@ -1,10 +1,28 @@
 package nntp
 
+import (
+	"context"
+	"errors"
+)
+
+// Responses per command that retrieves an article by message-id,
+// 201 for article as send by spool-mock.
+var retrieve = map[string][]Expect{
+	"article": []Expect{Expect{"220 ", false}, Expect{"201 ", false}, Expect{"430 ", true}},
+	"body":    []Expect{Expect{"222 ", false}, Expect{"430 ", true}},
+	"head":    []Expect{Expect{"221 ", false}, Expect{"430 ", true}},
+	"stat":    []Expect{Expect{"223 ", false}, Expect{"430 ", true}},
+}
+
 func (c *Client) Auth(user string, pass string) error {
-	if _, e := c.Send("authinfo user "+user, []Expect{Expect{"381 ", false}}); e != nil {
+	return c.AuthContext(context.Background(), user, pass)
+}
+
+func (c *Client) AuthContext(ctx context.Context, user string, pass string) error {
+	if _, e := c.SendContext(ctx, "authinfo user "+user, []Expect{Expect{"381 ", false}}); e != nil {
 		return e
 	}
-	if _, e := c.Send("authinfo pass "+pass, []Expect{Expect{"281 ", false}}); e != nil {
+	if _, e := c.SendContext(ctx, "authinfo pass "+pass, []Expect{Expect{"281 ", false}}); e != nil {
 		return e
 	}
 	c.Ready = true
@@ -12,35 +30,84 @@ func (c *Client) Auth(user string, pass string) error {
 }
 
 func (c *Client) Post() error {
-	if _, e := c.Send("POST", []Expect{Expect{"340 ", false}}); e != nil {
+	return c.PostContext(context.Background())
+}
+
+func (c *Client) PostContext(ctx context.Context) error {
+	if _, e := c.SendContext(ctx, "POST", []Expect{Expect{"340 ", false}}); e != nil {
 		return e
 	}
 	return nil
 }
 
 func (c *Client) PostClose() error {
-	c.w.WriteString(EOM)
-	if e := c.w.Flush(); e != nil {
-		return e
-	}
+	return c.PostCloseContext(context.Background())
+}
 
-	_, e := c.Expect([]Expect{Expect{"240 ", false}})
-	if e != nil {
+// Wait for 240 once the article is written and closed
+// with GetDotWriter.
+func (c *Client) PostCloseContext(ctx context.Context) error {
+	if _, e := c.ExpectContext(ctx, []Expect{Expect{"240 ", false}}); e != nil {
 		return e
 	}
 	return nil
 }
 
 func (c *Client) Article(msgid string) error {
-	if _, e := c.Send("article <"+msgid+">", []Expect{Expect{"201 ", false}}); e != nil {
+	return c.ArticleContext(context.Background(), msgid)
+}
+
+func (c *Client) ArticleContext(ctx context.Context, msgid string) error {
+	if _, e := c.SendContext(ctx, "article <"+msgid+">", retrieve["article"]); e != nil {
 		return e
 	}
 	return nil
 }
 
+func (c *Client) Stat(msgid string) (bool, error) {
+	return c.StatContext(context.Background(), msgid)
+}
+
+// Check if article exists without transferring it,
+// returns false on 430 (no such article).
+func (c *Client) StatContext(ctx context.Context, msgid string) (bool, error) {
+	return found(c.SendContext(ctx, "stat <"+msgid+">", retrieve["stat"]))
+}
+
+func (c *Client) Head(msgid string) (bool, error) {
+	return c.HeadContext(context.Background(), msgid)
+}
+
+// Request headers, read them with GetReader when found.
+func (c *Client) HeadContext(ctx context.Context, msgid string) (bool, error) {
+	return found(c.SendContext(ctx, "head <"+msgid+">", retrieve["head"]))
+}
+
+func (c *Client) Body(msgid string) (bool, error) {
+	return c.BodyContext(context.Background(), msgid)
+}
+
+// Request body, read it with GetReader when found.
+func (c *Client) BodyContext(ctx context.Context, msgid string) (bool, error) {
+	return found(c.SendContext(ctx, "body <"+msgid+">", retrieve["body"]))
+}
+
+// Convert 430 (no such article) into not found
+func found(_ string, e error) (bool, error) {
+	if errors.Is(e, ERR_NO_ARTICLE) {
+		return false, nil
+	}
+	return e == nil, e
+}
+
 func (c *Client) Close() error {
 	c.Ready = false
+	if c.conn == nil {
+		// Never connected
+		return nil
+	}
 	// Ignore any err
 	c.w.Write([]byte("QUIT\r\n"))
+	c.w.Flush()
 	return c.conn.Close()
 }
@@ -0,0 +1,79 @@
+package nntp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Returned when the server did not respond within Read/WriteTimeout.
+type TimeoutError struct {
+	Op    string        // read or write
+	After time.Duration // Configured timeout
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("NNTP %s timeout after %s", e.Op, e.After)
+}
+func (e *TimeoutError) Timeout() bool   { return true }
+func (e *TimeoutError) Temporary() bool { return true }
+
+// Refresh the deadline before every read/write so a stalled
+// server fails instead of blocking forever.
+type deadlineConn struct {
+	net.Conn
+	read  time.Duration
+	write time.Duration
+}
+
+func (d *deadlineConn) Read(b []byte) (int, error) {
+	if d.read > 0 {
+		if e := d.Conn.SetReadDeadline(time.Now().Add(d.read)); e != nil {
+			return 0, e
+		}
+	}
+	n, e := d.Conn.Read(b)
+	if ne, ok := e.(net.Error); ok && ne.Timeout() {
+		e = &TimeoutError{Op: "read", After: d.read}
+	}
+	return n, e
+}
+
+func (d *deadlineConn) Write(b []byte) (int, error) {
+	if d.write > 0 {
+		if e := d.Conn.SetWriteDeadline(time.Now().Add(d.write)); e != nil {
+			return 0, e
+		}
+	}
+	n, e := d.Conn.Write(b)
+	if ne, ok := e.(net.Error); ok && ne.Timeout() {
+		e = &TimeoutError{Op: "write", After: d.write}
+	}
+	return n, e
+}
+
+// Close the connection when ctx is cancelled before stop is called,
+// the NNTP-session is undefined after an aborted command anyway.
+func (c *Client) watch(ctx context.Context) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func(conn net.Conn) {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}(c.conn)
+	return func() { close(done) }
+}
+
+// Prefer the ctx error over the 'closed connection' error caused by watch.
+func ctxErr(ctx context.Context, e error) error {
+	if e != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return e
+}
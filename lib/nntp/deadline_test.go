@@ -0,0 +1,101 @@
+package nntp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+// Server sending the welcome and then nothing, it never reads
+// so writes stall once the socket buffers are full.
+func silent(t *testing.T, readTimeout time.Duration) *Client {
+	l, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	go func() {
+		defer l.Close()
+		conn, e := l.Accept()
+		if e != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("200 welcome\r\n"))
+		time.Sleep(5 * time.Second)
+	}()
+
+	c := New(l.Addr().String(), "test", false)
+	c.ReadTimeout = readTimeout
+	if e := c.InitContext(context.Background()); e != nil {
+		t.Fatal(e)
+	}
+	return c
+}
+
+func TestBodyDeadline(t *testing.T) {
+	// Stalls halfway through the article
+	c := scripted(t, map[string][]string{
+		"body <stall@test>": {"222 0 <stall@test>\r\nfirst line\r\n"},
+	})
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if ok, e := c.BodyContext(ctx, "stall@test"); !ok || e != nil {
+		t.Fatalf("Body expect found but got=%v %v", ok, e)
+	}
+	begin := time.Now()
+	_, e := ioutil.ReadAll(c.GetReaderContext(ctx))
+	if !errors.Is(e, context.DeadlineExceeded) {
+		t.Fatalf("Expect DeadlineExceeded but got=%v", e)
+	}
+	if d := time.Since(begin); d > 2*time.Second {
+		t.Fatalf("Read returned after %s", d)
+	}
+}
+
+func TestDotWriterCancel(t *testing.T) {
+	c := silent(t, 0)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	w := c.GetDotWriterContext(ctx)
+	line := append(bytes.Repeat([]byte("a"), 126), '\r', '\n')
+	begin := time.Now()
+	var e error
+	for i := 0; i < 1e6 && e == nil; i++ {
+		_, e = w.Write(line)
+	}
+	if e == nil {
+		e = w.Close()
+	}
+	if !errors.Is(e, context.DeadlineExceeded) {
+		t.Fatalf("Expect DeadlineExceeded but got=%v", e)
+	}
+	if d := time.Since(begin); d > 2*time.Second {
+		t.Fatalf("Write returned after %s", d)
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	c := silent(t, 100*time.Millisecond)
+	defer c.Close()
+
+	begin := time.Now()
+	_, e := c.Send("DATE", []Expect{Expect{"111 ", false}})
+	var timeout *TimeoutError
+	if !errors.As(e, &timeout) || timeout.Op != "read" || timeout.After != 100*time.Millisecond {
+		t.Fatalf("Expect read TimeoutError but got=%v", e)
+	}
+	if d := time.Since(begin); d > 2*time.Second {
+		t.Fatalf("Read returned after %s", d)
+	}
+	if f := NewFailure(e); f.Class != FAIL_TIMEOUT {
+		t.Fatalf("Class expect=%s but got=%s", FAIL_TIMEOUT, f.Class)
+	}
+}
@@ -1,68 +1,163 @@
-// Insipired on the dotReader from textproto but way simpler
-// to improve performance.
+// Multi-line data blocks (RFC 3977 3.1.1), inspired on the
+// dotReader from textproto but without the CRLF conversion so
+// bodies stay byte-exact.
 package nntp
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"io"
+	"time"
 )
 
 // End Of Stream
 var END = []byte("\r\n.\r\n")
 var END_SHORT = []byte(".\r\n")
 
-// Ignore EOF
-var testNoEOF bool
-
+// Reads one multi-line block line by line, removes the leading
+// dot of stuffed lines and stops before the terminator so the
+// response that follows (pipelining) stays in the buffer.
 type DotReader struct {
-	r        io.Reader
-	begin    bool
-	done     bool
-	shortEnd bool
+	r    *bufio.Reader
+	bol  bool // At begin of line
+	done bool
+
+	rest []byte // Part of the line that did not fit into b
+
+	timing *Timing // FirstByte and Terminator, optional
+	ctx    context.Context
+	stop   func() // Of the watch on ctx, optional
+}
+
+// A lone dot ends the block, also as first line.
+func NewDotReader(r io.Reader) *DotReader {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &DotReader{r: br, bol: true}
+}
+
+// Unstuffed content until the terminator, then io.EOF
+func (d *DotReader) Read(b []byte) (int, error) {
+	n, e := d.read(b)
+	if e != nil {
+		e = release(d.ctx, &d.stop, e)
+	}
+	return n, e
+}
 
-	buf []byte
-	pos int
+func (d *DotReader) read(b []byte) (n int, err error) {
+	for len(d.rest) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		line, e := d.r.ReadSlice('\n')
+		if e == io.EOF {
+			// Did not receive end of stream, error!
+			return 0, io.ErrUnexpectedEOF
+		}
+		if e != nil && e != bufio.ErrBufferFull {
+			return 0, e
+		}
+		if d.timing != nil && d.timing.FirstByte.IsZero() {
+			d.timing.FirstByte = time.Now()
+		}
+		if d.bol && line[0] == '.' {
+			if bytes.Equal(line, END_SHORT) {
+				d.done = true
+				if d.timing != nil {
+					d.timing.Terminator = time.Now()
+				}
+				continue
+			}
+			// Dot-stuffed line
+			line = line[1:]
+		}
+		// Long lines fill the buffer before the LF
+		d.bol = e == nil
+		d.rest = line
+	}
+
+	n = copy(b, d.rest)
+	d.rest = d.rest[n:]
+	return n, nil
 }
 
-func NewDotReader(r io.Reader, shortEnd bool) *DotReader {
-	return &DotReader{r: r, begin: true, shortEnd: shortEnd}
+// Dot-stuffs a multi-line block, Close writes the terminator.
+type DotWriter struct {
+	w   *bufio.Writer
+	bol bool // At begin of line
+
+	timing *Timing // Terminator, optional
+	ctx    context.Context
+	stop   func() // Of the watch on ctx, optional
 }
 
-// Just keep on reading until we found that END.
-func (d *DotReader) Read(b []byte) (n int, err error) {
-	if d.done {
-		return 0, io.EOF
+func NewDotWriter(w io.Writer) *DotWriter {
+	bw, ok := w.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriter(w)
 	}
-	n, e := d.r.Read(b)
-	if testNoEOF && e == io.EOF {
-		// Ignore EOF on unittesting
-		e = nil
+	return &DotWriter{w: bw, bol: true}
+}
+
+// Write b with a dot prepended to every line that begins with one
+func (d *DotWriter) Write(b []byte) (int, error) {
+	n := 0
+	for len(b) > 0 {
+		if d.bol && b[0] == '.' {
+			if e := d.w.WriteByte('.'); e != nil {
+				return n, release(d.ctx, &d.stop, e)
+			}
+		}
+		end := bytes.IndexByte(b, '\n') + 1
+		d.bol = end > 0
+		if end == 0 {
+			end = len(b)
+		}
+		m, e := d.w.Write(b[:end])
+		n += m
+		if e != nil {
+			return n, release(d.ctx, &d.stop, e)
+		}
+		b = b[end:]
 	}
+	return n, nil
+}
 
-	// Remember last 5 bytes so we can find EOF
-	// if we receive the EOF in parts..
-	if n >= 5 {
-		d.buf = b[n-5 : n]
-		d.pos = 5
-	} else if n > 0 {
-		// TODO: User can OOM when slowly adding data?
-		// few bytes
-		d.buf = append(d.buf, b[:n]...)
-		d.pos += n
+// End the last line with CRLF when needed, write the terminator
+// and flush.
+func (d *DotWriter) Close() error {
+	e := d.close()
+	if e == nil && d.timing != nil {
+		d.timing.Terminator = time.Now()
 	}
+	return release(d.ctx, &d.stop, e)
+}
 
-	if d.begin && d.shortEnd && bytes.Index(b[0:len(END_SHORT)], END_SHORT) == 0 {
-		d.done = true
-		e = io.EOF
+func (d *DotWriter) close() error {
+	if !d.bol {
+		if _, e := d.w.WriteString(EOF); e != nil {
+			return e
+		}
 	}
-	d.begin = false
-	if idx := bytes.Index(d.buf, END); idx != -1 {
-		d.done = true
-		e = io.EOF
+	if _, e := d.w.Write(END_SHORT); e != nil {
+		return e
 	}
-	if !d.done && e == io.EOF {
-		// Did not receive end of stream, error!
-		e = io.ErrUnexpectedEOF
+	return d.w.Flush()
+}
+
+// Stop the watch on ctx once a block ends or fails and prefer
+// the ctx error over the closed connection.
+func release(ctx context.Context, stop *func(), e error) error {
+	if *stop != nil {
+		(*stop)()
+		*stop = nil
 	}
-	return n, e
+	if ctx == nil || e == io.EOF {
+		return e
+	}
+	return ctxErr(ctx, e)
 }
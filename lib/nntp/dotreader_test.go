@@ -0,0 +1,126 @@
+package nntp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDotReader(t *testing.T) {
+	tests := []struct {
+		In     string
+		Expect string
+	}{
+		{".\r\n", ""},
+		{"a\r\n.\r\n", "a\r\n"},
+		{"..\r\n..a\r\n.b\r\n.\r\n", ".\r\n.a\r\nb\r\n"},
+		{"a.\r\n\r\n.\r\n", "a.\r\n\r\n"},
+		{"\r\n.\r\n", "\r\n"},
+		{"a\n.\r\n", "a\n"},
+		{".\n.\r\n", "\n"},
+	}
+	for _, test := range tests {
+		// Terminator split over reads
+		for _, r := range []io.Reader{
+			strings.NewReader(test.In),
+			iotest.OneByteReader(strings.NewReader(test.In)),
+			iotest.HalfReader(strings.NewReader(test.In)),
+		} {
+			out, e := ioutil.ReadAll(NewDotReader(r))
+			if e != nil {
+				t.Fatalf("Input(%q) %s", test.In, e)
+			}
+			if string(out) != test.Expect {
+				t.Fatalf("Input(%q) expect=%q but got=%q", test.In, test.Expect, out)
+			}
+		}
+	}
+}
+
+func TestDotReaderNext(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("a\r\n.\r\n222 next\r\n"))
+	if _, e := ioutil.ReadAll(NewDotReader(r)); e != nil {
+		t.Fatal(e)
+	}
+	if next, _ := r.ReadString('\n'); next != "222 next\r\n" {
+		t.Fatalf("Read past terminator, left=%q", next)
+	}
+}
+
+func TestDotReaderTruncated(t *testing.T) {
+	for _, in := range []string{"", "a\r\n", "a\r\n.", "a\r\n.\r"} {
+		if _, e := ioutil.ReadAll(NewDotReader(strings.NewReader(in))); e != io.ErrUnexpectedEOF {
+			t.Fatalf("Input(%q) expect=%s but got=%v", in, io.ErrUnexpectedEOF, e)
+		}
+	}
+}
+
+func TestDotWriter(t *testing.T) {
+	tests := []struct {
+		In     string
+		Expect string
+	}{
+		{"", ".\r\n"},
+		{"a", "a\r\n.\r\n"},
+		{".\r\n", "..\r\n.\r\n"},
+		{"a\r\n.b\r\n", "a\r\n..b\r\n.\r\n"},
+		{"a.b", "a.b\r\n.\r\n"},
+	}
+	for _, test := range tests {
+		out := new(bytes.Buffer)
+		w := NewDotWriter(out)
+		// One byte at a time must give the same stuffing
+		for i := 0; i < len(test.In); i++ {
+			if _, e := w.Write([]byte{test.In[i]}); e != nil {
+				t.Fatal(e)
+			}
+		}
+		if e := w.Close(); e != nil {
+			t.Fatal(e)
+		}
+		if out.String() != test.Expect {
+			t.Fatalf("Input(%q) expect=%q but got=%q", test.In, test.Expect, out.String())
+		}
+	}
+}
+
+func FuzzDotRoundTrip(f *testing.F) {
+	for _, seed := range []string{"", ".", "..\r\n", ".\r\n", "a\r\n.\r\n.", "\r\n.\r\n", "=ybegin\r\n.x\n"} {
+		f.Add([]byte(seed))
+	}
+	// Lines longer than the bufio buffer
+	f.Add([]byte(strings.Repeat("x", 4095) + ".\r\n.\r\n" + strings.Repeat(".", 9000)))
+	f.Fuzz(func(t *testing.T, body []byte) {
+		wire := new(bytes.Buffer)
+		w := NewDotWriter(wire)
+		if _, e := w.Write(body); e != nil {
+			t.Fatal(e)
+		}
+		if e := w.Close(); e != nil {
+			t.Fatal(e)
+		}
+		// Followed by the next response
+		wire.WriteString("240 ok\r\n")
+
+		r := bufio.NewReader(iotest.HalfReader(wire))
+		out, e := ioutil.ReadAll(NewDotReader(r))
+		if e != nil {
+			t.Fatal(e)
+		}
+		expect := body
+		if len(body) > 0 && body[len(body)-1] != '\n' {
+			// Writer ends the last line
+			expect = append(append([]byte{}, body...), EOF...)
+		}
+		if !bytes.Equal(out, expect) {
+			t.Fatalf("Body(%q) expect=%q but got=%q", body, expect, out)
+		}
+		if next, _ := r.ReadString('\n'); next != "240 ok\r\n" {
+			t.Fatalf("Body(%q) read past terminator, left=%q", body, next)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+// Transit commands, IHAVE (RFC 3977 6.3.2) and the streaming
+// feed CHECK/TAKETHIS (RFC 4644). Articles are written with
+// GetDotWriter like POST.
+package nntp
+
+import (
+	"context"
+	"errors"
+)
+
+func (c *Client) IHave(msgid string) (bool, error) {
+	return c.IHaveContext(context.Background(), msgid)
+}
+
+// Offer msgid, false on 435 (not wanted). Write the article
+// with GetDotWriter when wanted and close with IHaveClose.
+func (c *Client) IHaveContext(ctx context.Context, msgid string) (bool, error) {
+	return wanted(c.SendContext(ctx, "IHAVE <"+msgid+">", []Expect{Expect{"335 ", false}, Expect{"435 ", true}}))
+}
+
+func (c *Client) IHaveClose() error {
+	return c.IHaveCloseContext(context.Background())
+}
+
+// Wait for 235 once the article is written, 436 (try later)
+// and 437 (rejected) are errors.
+func (c *Client) IHaveCloseContext(ctx context.Context) error {
+	if _, e := c.ExpectContext(ctx, []Expect{Expect{"235 ", false}}); e != nil {
+		return e
+	}
+	return nil
+}
+
+func (c *Client) ModeStream() error {
+	return c.ModeStreamContext(context.Background())
+}
+
+// Switch to streaming, required before CHECK and TAKETHIS
+func (c *Client) ModeStreamContext(ctx context.Context) error {
+	if _, e := c.SendContext(ctx, "MODE STREAM", []Expect{Expect{"203 ", false}}); e != nil {
+		return e
+	}
+	return nil
+}
+
+func (c *Client) Check(msgid string) (bool, error) {
+	return c.CheckContext(context.Background(), msgid)
+}
+
+// Ask if msgid is wanted, false on 438 (not wanted) and
+// 431 (try later) is an error.
+func (c *Client) CheckContext(ctx context.Context, msgid string) (bool, error) {
+	return wanted(c.SendContext(ctx, "CHECK <"+msgid+">", []Expect{Expect{"238 ", false}, Expect{"438 ", true}}))
+}
+
+func (c *Client) TakeThis(msgid string) error {
+	return c.TakeThisContext(context.Background(), msgid)
+}
+
+// Send msgid without waiting for a response, write the
+// article with GetDotWriter and close with TakeThisClose.
+func (c *Client) TakeThisContext(ctx context.Context, msgid string) error {
+	return c.write(ctx, "TAKETHIS <"+msgid+">")
+}
+
+func (c *Client) TakeThisClose() error {
+	return c.TakeThisCloseContext(context.Background())
+}
+
+// Wait for 239 once the article is written, 439 (rejected)
+// matches ERR_STREAM_REJECTED.
+func (c *Client) TakeThisCloseContext(ctx context.Context) error {
+	if _, e := c.ExpectContext(ctx, []Expect{Expect{"239 ", false}, Expect{"439 ", true}}); e != nil {
+		return e
+	}
+	return nil
+}
+
+// Convert 435/438 (not wanted) into false
+func wanted(_ string, e error) (bool, error) {
+	if errors.Is(e, ERR_NOT_WANTED) || errors.Is(e, ERR_STREAM_NOT_WANTED) {
+		return false, nil
+	}
+	return e == nil, e
+}
@@ -0,0 +1,93 @@
+package nntp
+
+import (
+	"errors"
+	"testing"
+)
+
+// Write a minimal article over the open command
+func writeArticle(t *testing.T, c *Client, msgid string) {
+	w := c.GetDotWriter()
+	if _, e := w.Write([]byte("Message-ID: <" + msgid + ">\r\n\r\n.dot stuffed\r\n")); e != nil {
+		t.Fatal(e)
+	}
+	if e := w.Close(); e != nil {
+		t.Fatal(e)
+	}
+}
+
+func TestIHave(t *testing.T) {
+	c := scripted(t, map[string][]string{
+		"IHAVE <new@test>":  {"335 send it\r\n"},
+		"IHAVE <dup@test>":  {"435 duplicate\r\n"},
+		"IHAVE <spam@test>": {"335 send it\r\n"},
+		".":                 {"235 transferred\r\n", "437 rejected\r\n"},
+	})
+	defer c.Close()
+
+	ok, e := c.IHave("new@test")
+	if !ok || e != nil {
+		t.Fatalf("IHave expect wanted but got=%v %v", ok, e)
+	}
+	writeArticle(t, c, "new@test")
+	if e := c.IHaveClose(); e != nil {
+		t.Fatal(e)
+	}
+
+	if ok, e := c.IHave("dup@test"); ok || e != nil {
+		t.Fatalf("IHave expect not wanted but got=%v %v", ok, e)
+	}
+
+	if ok, e := c.IHave("spam@test"); !ok || e != nil {
+		t.Fatalf("IHave expect wanted but got=%v %v", ok, e)
+	}
+	writeArticle(t, c, "spam@test")
+	if e := c.IHaveClose(); !errors.Is(e, ERR_TRANSFER_REJECTED) {
+		t.Fatalf("Expect ERR_TRANSFER_REJECTED but got=%v", e)
+	}
+}
+
+func TestStream(t *testing.T) {
+	c := scripted(t, map[string][]string{
+		"MODE STREAM":          {"203 streaming ok\r\n"},
+		"CHECK <new@test>":     {"238 <new@test>\r\n"},
+		"CHECK <dup@test>":     {"438 <dup@test>\r\n"},
+		"CHECK <later@test>":   {"431 <later@test>\r\n"},
+		"TAKETHIS <new@test>":  {"239 <new@test>\r\n"},
+		"TAKETHIS <spam@test>": {"439 <spam@test>\r\n"},
+	})
+	defer c.Close()
+
+	if e := c.ModeStream(); e != nil {
+		t.Fatal(e)
+	}
+	if ok, e := c.Check("new@test"); !ok || e != nil {
+		t.Fatalf("Check expect wanted but got=%v %v", ok, e)
+	}
+	if ok, e := c.Check("dup@test"); ok || e != nil {
+		t.Fatalf("Check expect not wanted but got=%v %v", ok, e)
+	}
+	var res *ResponseError
+	if _, e := c.Check("later@test"); !errors.As(e, &res) || !res.Temporary() {
+		t.Fatalf("Check expect temporary error but got=%v", e)
+	}
+
+	if e := c.TakeThis("new@test"); e != nil {
+		t.Fatal(e)
+	}
+	writeArticle(t, c, "new@test")
+	if e := c.TakeThisClose(); e != nil {
+		t.Fatal(e)
+	}
+	if c.Timing.Terminator.IsZero() || c.Timing.Status.Before(c.Timing.Terminator) {
+		t.Fatalf("Timing expect terminator before 239 but got=%+v", c.Timing)
+	}
+
+	if e := c.TakeThis("spam@test"); e != nil {
+		t.Fatal(e)
+	}
+	writeArticle(t, c, "spam@test")
+	if e := c.TakeThisClose(); !errors.Is(e, ERR_STREAM_REJECTED) {
+		t.Fatalf("Expect ERR_STREAM_REJECTED but got=%v", e)
+	}
+}
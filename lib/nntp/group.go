@@ -0,0 +1,310 @@
+// Group and overview commands (RFC 3977 6.1, 7.6 and 8), the
+// multi-line results are parsed line by line from GetReader so
+// large groups are never held in memory.
+package nntp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+const CAP_OVER = "OVER"
+const CAP_HDR = "HDR"
+
+// Newsgroup as selected by GROUP or listed by LIST ACTIVE
+type Group struct {
+	Name   string
+	Count  int64  // Estimated articles, only set by GROUP
+	Low    int64  // Lowest article number
+	High   int64  // Highest article number
+	Status string // y, n or m (moderated), only set by LIST ACTIVE
+}
+
+// One line of OVER/XOVER (RFC 3977 8.3.2)
+type Overview struct {
+	Number     int64
+	Subject    string
+	From       string
+	Date       string
+	MessageID  string // Without angle brackets
+	References string
+	Bytes      int64
+	Lines      int64
+	Extra      []string // Fields after Lines, e.g. Xref: full
+}
+
+// One line of HDR/XHDR, Number is 0 when asked by message-id
+type Header struct {
+	Number int64
+	Value  string
+}
+
+// Lines of a multi-line block without CRLF, Next returns
+// io.EOF after the terminator.
+type lineReader struct {
+	r   *bufio.Reader
+	cmd string // For ProtocolError
+}
+
+func newLineReader(r io.Reader, cmd string) lineReader {
+	return lineReader{r: bufio.NewReader(r), cmd: cmd}
+}
+
+func (l lineReader) next() (string, error) {
+	line, e := l.r.ReadString('\n')
+	if e == io.EOF && line != "" {
+		// Last line without CRLF
+		e = nil
+	}
+	if e != nil {
+		return "", e
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (l lineReader) protocolError(line string) error {
+	return &ProtocolError{Line: line, Cmd: l.cmd}
+}
+
+// Article numbers of LISTGROUP
+type NumberReader struct {
+	lineReader
+}
+
+func NewNumberReader(r io.Reader) *NumberReader {
+	return &NumberReader{newLineReader(r, "LISTGROUP")}
+}
+
+func (r *NumberReader) Next() (int64, error) {
+	line, e := r.next()
+	if e != nil {
+		return 0, e
+	}
+	n, e := strconv.ParseInt(strings.TrimSpace(line), 10, 64)
+	if e != nil {
+		return 0, r.protocolError(line)
+	}
+	return n, nil
+}
+
+// Lines of OVER/XOVER
+type OverviewReader struct {
+	lineReader
+}
+
+func NewOverviewReader(r io.Reader) *OverviewReader {
+	return &OverviewReader{newLineReader(r, "OVER")}
+}
+
+func (r *OverviewReader) Next() (Overview, error) {
+	line, e := r.next()
+	if e != nil {
+		return Overview{}, e
+	}
+	o, ok := parseOverview(line)
+	if !ok {
+		return Overview{}, r.protocolError(line)
+	}
+	return o, nil
+}
+
+func parseOverview(line string) (Overview, bool) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 8 {
+		return Overview{}, false
+	}
+	var nums [3]int64
+	for i, idx := range []int{0, 6, 7} {
+		n, e := strconv.ParseInt(strings.TrimSpace(fields[idx]), 10, 64)
+		if e != nil {
+			if idx == 0 {
+				return Overview{}, false
+			}
+			// Bytes and Lines may be empty
+			n = 0
+		}
+		nums[i] = n
+	}
+	return Overview{
+		Number:     nums[0],
+		Subject:    fields[1],
+		From:       fields[2],
+		Date:       fields[3],
+		MessageID:  strings.TrimSuffix(strings.TrimPrefix(fields[4], "<"), ">"),
+		References: fields[5],
+		Bytes:      nums[1],
+		Lines:      nums[2],
+		Extra:      fields[8:],
+	}, true
+}
+
+// Lines of HDR/XHDR
+type HeaderReader struct {
+	lineReader
+}
+
+func NewHeaderReader(r io.Reader) *HeaderReader {
+	return &HeaderReader{newLineReader(r, "HDR")}
+}
+
+func (r *HeaderReader) Next() (Header, error) {
+	line, e := r.next()
+	if e != nil {
+		return Header{}, e
+	}
+	num, value := line, ""
+	if idx := strings.IndexByte(line, ' '); idx != -1 {
+		num, value = line[:idx], line[idx+1:]
+	}
+	n, e := strconv.ParseInt(num, 10, 64)
+	if e != nil {
+		return Header{}, r.protocolError(line)
+	}
+	return Header{Number: n, Value: value}, nil
+}
+
+// Lines of LIST ACTIVE
+type ActiveReader struct {
+	lineReader
+}
+
+func NewActiveReader(r io.Reader) *ActiveReader {
+	return &ActiveReader{newLineReader(r, "LIST ACTIVE")}
+}
+
+func (r *ActiveReader) Next() (Group, error) {
+	line, e := r.next()
+	if e != nil {
+		return Group{}, e
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 4 {
+		return Group{}, r.protocolError(line)
+	}
+	high, eh := strconv.ParseInt(fields[1], 10, 64)
+	low, el := strconv.ParseInt(fields[2], 10, 64)
+	if eh != nil || el != nil {
+		return Group{}, r.protocolError(line)
+	}
+	return Group{Name: fields[0], High: high, Low: low, Status: fields[3]}, nil
+}
+
+// 211 count low high group
+func parseGroup(l string) (Group, bool) {
+	fields := strings.Fields(l)
+	if len(fields) < 5 {
+		return Group{}, false
+	}
+	var nums [3]int64
+	for i := range nums {
+		n, e := strconv.ParseInt(fields[1+i], 10, 64)
+		if e != nil {
+			return Group{}, false
+		}
+		nums[i] = n
+	}
+	return Group{Name: fields[4], Count: nums[0], Low: nums[1], High: nums[2]}, true
+}
+
+func (c *Client) Group(name string) (Group, error) {
+	return c.GroupContext(context.Background(), name)
+}
+
+// Select group, 411 (no such group) matches ERR_NO_GROUP
+func (c *Client) GroupContext(ctx context.Context, name string) (Group, error) {
+	l, e := c.SendContext(ctx, "GROUP "+name, []Expect{Expect{"211 ", false}, Expect{"411 ", true}})
+	if e != nil {
+		return Group{}, e
+	}
+	g, ok := parseGroup(l)
+	if !ok {
+		return Group{}, &ProtocolError{Line: l, Cmd: c.cmd}
+	}
+	return g, nil
+}
+
+func (c *Client) ListGroup(name string, rng string) (Group, *NumberReader, error) {
+	return c.ListGroupContext(context.Background(), name, rng)
+}
+
+// Select group and list its article numbers within rng
+// (e.g. 100-200, empty for all), read them until io.EOF
+// before the next command.
+func (c *Client) ListGroupContext(ctx context.Context, name string, rng string) (Group, *NumberReader, error) {
+	cmd := "LISTGROUP " + name
+	if rng != "" {
+		cmd += " " + rng
+	}
+	l, e := c.SendContext(ctx, cmd, []Expect{Expect{"211 ", false}, Expect{"411 ", true}})
+	if e != nil {
+		return Group{}, nil, e
+	}
+	g, ok := parseGroup(l)
+	if !ok {
+		// Drain the block so the connection stays usable
+		io.Copy(ioutil.Discard, c.GetReaderContext(ctx))
+		return Group{}, nil, &ProtocolError{Line: l, Cmd: c.cmd}
+	}
+	return g, NewNumberReader(c.GetReaderContext(ctx)), nil
+}
+
+func (c *Client) Over(rng string) (*OverviewReader, error) {
+	return c.OverContext(context.Background(), rng)
+}
+
+// Overview of rng (e.g. 100-200 or <msgid>) in the selected
+// group, OVER when advertised in c.Caps else XOVER.
+func (c *Client) OverContext(ctx context.Context, rng string) (*OverviewReader, error) {
+	cmd := "XOVER"
+	if c.Caps.Has(CAP_OVER) {
+		cmd = "OVER"
+	}
+	if rng != "" {
+		cmd += " " + rng
+	}
+	if _, e := c.SendContext(ctx, cmd, []Expect{Expect{"224 ", false}}); e != nil {
+		return nil, e
+	}
+	return NewOverviewReader(c.GetReaderContext(ctx)), nil
+}
+
+func (c *Client) Hdr(field string, rng string) (*HeaderReader, error) {
+	return c.HdrContext(context.Background(), field, rng)
+}
+
+// Header field of rng (e.g. 100-200 or <msgid>) in the selected
+// group, HDR when advertised in c.Caps else XHDR.
+func (c *Client) HdrContext(ctx context.Context, field string, rng string) (*HeaderReader, error) {
+	cmd, expect := "XHDR", "221 "
+	if c.Caps.Has(CAP_HDR) {
+		cmd, expect = "HDR", "225 "
+	}
+	cmd += " " + field
+	if rng != "" {
+		cmd += " " + rng
+	}
+	if _, e := c.SendContext(ctx, cmd, []Expect{Expect{expect, false}}); e != nil {
+		return nil, e
+	}
+	return NewHeaderReader(c.GetReaderContext(ctx)), nil
+}
+
+func (c *Client) ListActive(wildmat string) (*ActiveReader, error) {
+	return c.ListActiveContext(context.Background(), wildmat)
+}
+
+// Groups matching wildmat (e.g. alt.binaries.*, empty for all)
+func (c *Client) ListActiveContext(ctx context.Context, wildmat string) (*ActiveReader, error) {
+	cmd := "LIST ACTIVE"
+	if wildmat != "" {
+		cmd += " " + wildmat
+	}
+	if _, e := c.SendContext(ctx, cmd, []Expect{Expect{"215 ", false}}); e != nil {
+		return nil, e
+	}
+	return NewActiveReader(c.GetReaderContext(ctx)), nil
+}
@@ -0,0 +1,154 @@
+package nntp
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	c := scripted(t, map[string][]string{
+		"GROUP alt.binaries.test": {"211 1234 3000234 3002322 alt.binaries.test\r\n"},
+		"GROUP alt.nope":          {"411 No such newsgroup\r\n"},
+		"LISTGROUP alt.binaries.test 3000234-": {
+			"211 3 3000234 3002322 alt.binaries.test list follows\r\n3000234\r\n3000237\r\n3002322\r\n.\r\n",
+		},
+	})
+	defer c.Close()
+
+	g, e := c.Group("alt.binaries.test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	expect := Group{Name: "alt.binaries.test", Count: 1234, Low: 3000234, High: 3002322}
+	if g != expect {
+		t.Fatalf("Group expect=%+v but got=%+v", expect, g)
+	}
+	if _, e := c.Group("alt.nope"); !errors.Is(e, ERR_NO_GROUP) {
+		t.Fatalf("Expect ERR_NO_GROUP but got=%v", e)
+	}
+
+	g, r, e := c.ListGroup("alt.binaries.test", "3000234-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if g.Count != 3 {
+		t.Fatalf("Count expect=3 but got=%d", g.Count)
+	}
+	var nums []int64
+	for {
+		n, e := r.Next()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			t.Fatal(e)
+		}
+		nums = append(nums, n)
+	}
+	if !reflect.DeepEqual(nums, []int64{3000234, 3000237, 3002322}) {
+		t.Fatalf("Numbers got=%v", nums)
+	}
+}
+
+func TestOver(t *testing.T) {
+	over := "224 Overview information follows\r\n" +
+		"3000234\tI am just a test article\t\"Demo User\" <nobody@example.com>\t6 Oct 1998 04:38:40 -0500\t<45223423@example.com>\t<45454@example.net>\t1234\t17\tXref: news.example.com misc.test:3000363\r\n" +
+		"3000235\tAnother test article\tnobody@nowhere.to (Demo User)\t6 Oct 1998 04:38:45 -0500\t<45223425@to.to>\t\t4818\t37\t\tDistribution: fi\r\n" +
+		".\r\n"
+	for _, test := range []struct {
+		Caps string
+		Cmd  string
+	}{
+		{"101 list\r\nREADER\r\n.\r\n", "XOVER 3000234-3000235"},
+		{"101 list\r\nREADER\r\nOVER MSGID\r\n.\r\n", "OVER 3000234-3000235"},
+	} {
+		c := scripted(t, map[string][]string{
+			"CAPABILITIES": {test.Caps},
+			test.Cmd:       {over},
+		})
+		if _, e := c.Capabilities(); e != nil {
+			t.Fatal(e)
+		}
+		r, e := c.Over("3000234-3000235")
+		if e != nil {
+			t.Fatalf("%s: %s", test.Cmd, e)
+		}
+		var got []Overview
+		for {
+			o, e := r.Next()
+			if e == io.EOF {
+				break
+			}
+			if e != nil {
+				t.Fatal(e)
+			}
+			got = append(got, o)
+		}
+		c.Close()
+
+		if len(got) != 2 {
+			t.Fatalf("%s: expect 2 lines but got=%d", test.Cmd, len(got))
+		}
+		first := got[0]
+		if first.Number != 3000234 || first.MessageID != "45223423@example.com" || first.Bytes != 1234 || first.Lines != 17 {
+			t.Fatalf("%s: first line got=%+v", test.Cmd, first)
+		}
+		if !reflect.DeepEqual(got[1].Extra, []string{"", "Distribution: fi"}) {
+			t.Fatalf("%s: Extra got=%q", test.Cmd, got[1].Extra)
+		}
+	}
+}
+
+func TestHdr(t *testing.T) {
+	c := scripted(t, map[string][]string{
+		"XHDR Subject 3000234-3000235": {"221 Header follows\r\n3000234 I am just a test article\r\n3000235\r\n.\r\n"},
+	})
+	defer c.Close()
+
+	r, e := c.Hdr("Subject", "3000234-3000235")
+	if e != nil {
+		t.Fatal(e)
+	}
+	var got []Header
+	for {
+		h, e := r.Next()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			t.Fatal(e)
+		}
+		got = append(got, h)
+	}
+	expect := []Header{{3000234, "I am just a test article"}, {3000235, ""}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("Headers expect=%+v but got=%+v", expect, got)
+	}
+}
+
+func TestListActive(t *testing.T) {
+	c := scripted(t, map[string][]string{
+		"LIST ACTIVE alt.binaries.*": {"215 list of newsgroups follows\r\nalt.binaries.test 3002322 3000234 y\r\nalt.binaries.bogus\r\n.\r\n"},
+	})
+	defer c.Close()
+
+	r, e := c.ListActive("alt.binaries.*")
+	if e != nil {
+		t.Fatal(e)
+	}
+	g, e := r.Next()
+	if e != nil {
+		t.Fatal(e)
+	}
+	expect := Group{Name: "alt.binaries.test", Low: 3000234, High: 3002322, Status: "y"}
+	if g != expect {
+		t.Fatalf("Group expect=%+v but got=%+v", expect, g)
+	}
+	var proto *ProtocolError
+	if _, e := r.Next(); !errors.As(e, &proto) || !strings.Contains(proto.Line, "bogus") {
+		t.Fatalf("Expect ProtocolError but got=%v", e)
+	}
+}
@@ -0,0 +1,92 @@
+package nntp
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ERR_PIPELINE_FULL = errors.New("Pipeline full")
+var ERR_PIPELINE_EMPTY = errors.New("Pipeline empty, nothing to expect")
+
+// Keeps up to Depth commands in flight on one connection, the
+// server answers in the order they were sent (RFC 3977 3.5).
+type Pipeline struct {
+	Depth int
+
+	c     *Client
+	queue []pending
+}
+
+type pending struct {
+	cmd      string
+	prefixes []Expect
+	sent     time.Time
+}
+
+// Pipeline on c, depth below 1 sends one command at a time
+func (c *Client) Pipeline(depth int) *Pipeline {
+	if depth < 1 {
+		depth = 1
+	}
+	return &Pipeline{Depth: depth, c: c}
+}
+
+// Commands waiting for their response
+func (p *Pipeline) Pending() int {
+	return len(p.queue)
+}
+
+// Room for another command
+func (p *Pipeline) Full() bool {
+	return len(p.queue) >= p.Depth
+}
+
+// Write cmd without waiting for the response, read it later
+// with ExpectContext.
+func (p *Pipeline) SendContext(ctx context.Context, cmd string, prefixes []Expect) error {
+	if p.Full() {
+		return ERR_PIPELINE_FULL
+	}
+	c := p.c
+	c.log("C(%s) >> %s", c.Name, cmd)
+	stop := c.watch(ctx)
+	_, e := c.w.WriteString(cmd + EOF)
+	if e == nil {
+		e = c.w.Flush()
+	}
+	stop()
+	if e != nil {
+		return ctxErr(ctx, e)
+	}
+	p.queue = append(p.queue, pending{cmd: redact(cmd), prefixes: prefixes, sent: time.Now()})
+	return nil
+}
+
+// Read the response of the oldest command, Client.Timing and
+// Client.Status describe that command afterwards.
+func (p *Pipeline) ExpectContext(ctx context.Context) (string, error) {
+	if len(p.queue) == 0 {
+		return "", ERR_PIPELINE_EMPTY
+	}
+	cmd := p.queue[0]
+	p.queue = p.queue[1:]
+	p.c.Timing = Timing{Sent: cmd.sent}
+	p.c.cmd = cmd.cmd
+	return p.c.ExpectContext(ctx, cmd.prefixes)
+}
+
+// Queue verb (article, body, head or stat) for msgid
+func (p *Pipeline) RetrieveContext(ctx context.Context, verb string, msgid string) error {
+	prefixes, ok := retrieve[verb]
+	if !ok {
+		return errors.New("Invalid retrieve command: " + verb)
+	}
+	return p.SendContext(ctx, verb+" <"+msgid+">", prefixes)
+}
+
+// Response of the oldest retrieve, false on 430 (no such article).
+// Read the block with GetReader when found (except for stat).
+func (p *Pipeline) FoundContext(ctx context.Context) (bool, error) {
+	return found(p.ExpectContext(ctx))
+}
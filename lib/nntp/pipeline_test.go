@@ -0,0 +1,100 @@
+package nntp
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+)
+
+// Answer every stat/body in order, <missing> is not found
+func serve(t *testing.T, l net.Listener) {
+	conn, e := l.Accept()
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	conn.Write([]byte("200 welcome\r\n"))
+	for {
+		line, e := r.ReadString('\n')
+		if e != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) != 2 {
+			return
+		}
+		var res string
+		switch {
+		case args[1] == "<missing>":
+			res = "430 no such article\r\n"
+		case args[0] == "stat":
+			res = "223 0 " + args[1] + "\r\n"
+		default:
+			res = "222 0 " + args[1] + "\r\nbody of " + args[1] + "\r\n..stuffed\r\n.\r\n"
+		}
+		conn.Write([]byte(res))
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	l, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer l.Close()
+	go serve(t, l)
+
+	ctx := context.Background()
+	c := New(l.Addr().String(), "test", false)
+	if e := c.InitContext(ctx); e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+
+	p := c.Pipeline(3)
+	for _, cmd := range [][2]string{{"body", "a@b"}, {"stat", "missing"}, {"body", "c@d"}} {
+		if e := p.RetrieveContext(ctx, cmd[0], cmd[1]); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if !p.Full() {
+		t.Fatal("Pipeline expect full at depth 3")
+	}
+	if e := p.RetrieveContext(ctx, "stat", "e@f"); e != ERR_PIPELINE_FULL {
+		t.Fatalf("Send on full pipeline expect=%s but got=%v", ERR_PIPELINE_FULL, e)
+	}
+
+	for _, expect := range []string{"a@b", "", "c@d"} {
+		found, e := p.FoundContext(ctx)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if found != (expect != "") {
+			t.Fatalf("Response of %q found=%t", expect, found)
+		}
+		if !found {
+			continue
+		}
+		if c.Timing.Sent.IsZero() || c.Timing.Status.Before(c.Timing.Sent) {
+			t.Fatalf("Invalid timing %+v", c.Timing)
+		}
+		body, e := ioutil.ReadAll(c.GetReader())
+		if e != nil {
+			t.Fatal(e)
+		}
+		if string(body) != "body of <"+expect+">\r\n.stuffed\r\n" {
+			t.Fatalf("Body expect=%s but got=%q", expect, body)
+		}
+		if c.Timing.Terminator.IsZero() {
+			t.Fatal("Terminator not stamped")
+		}
+	}
+	if _, e := p.ExpectContext(ctx); e != ERR_PIPELINE_EMPTY {
+		t.Fatalf("Expect on empty pipeline expect=%s but got=%v", ERR_PIPELINE_EMPTY, e)
+	}
+}
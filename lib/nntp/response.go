@@ -0,0 +1,165 @@
+package nntp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Status line of a response (RFC 3977 3.2)
+type Response struct {
+	Code int
+	Msg  string // Text after the code
+	Cmd  string // Command it answers, empty for the welcome
+}
+
+func (r Response) String() string {
+	return fmt.Sprintf("%d %s", r.Code, r.Msg)
+}
+
+// Split line into code and message
+func ParseResponse(line string) (Response, error) {
+	if len(line) < 3 || (len(line) > 3 && line[3] != ' ') {
+		return Response{}, &ProtocolError{Line: line}
+	}
+	code, e := strconv.Atoi(line[:3])
+	if e != nil || code < 100 || code > 599 {
+		return Response{}, &ProtocolError{Line: line}
+	}
+	res := Response{Code: code}
+	if len(line) > 4 {
+		res.Msg = line[4:]
+	}
+	return res, nil
+}
+
+// Response with a 4xx (transient) or 5xx (permanent) code,
+// errors.Is matches the ERR_ variables by code.
+type ResponseError struct {
+	Response
+}
+
+func (e *ResponseError) Error() string {
+	if e.Cmd == "" {
+		return e.Response.String()
+	}
+	return fmt.Sprintf("%s: %s", e.Cmd, e.Response)
+}
+
+// 4xx, the same command may succeed later
+func (e *ResponseError) Temporary() bool {
+	return e.Code < 500
+}
+
+func (e *ResponseError) Is(target error) bool {
+	if target == ERR_RANGE {
+		return true
+	}
+	t, ok := target.(*ResponseError)
+	return ok && t.Code == e.Code
+}
+
+var ERR_NO_GROUP = &ResponseError{Response{Code: 411, Msg: "No such newsgroup"}}
+var ERR_NO_ARTICLE = &ResponseError{Response{Code: 430, Msg: "No such article"}}
+var ERR_NOT_WANTED = &ResponseError{Response{Code: 435, Msg: "Article not wanted"}}
+var ERR_TRANSFER_REJECTED = &ResponseError{Response{Code: 437, Msg: "Transfer rejected"}}
+var ERR_STREAM_NOT_WANTED = &ResponseError{Response{Code: 438, Msg: "Article not wanted"}}
+var ERR_STREAM_REJECTED = &ResponseError{Response{Code: 439, Msg: "Transfer rejected"}}
+var ERR_POSTING_FAILED = &ResponseError{Response{Code: 441, Msg: "Posting failed"}}
+var ERR_AUTH_REJECTED = &ResponseError{Response{Code: 481, Msg: "Authentication failed/rejected"}}
+var ERR_ACCESS_DENIED = &ResponseError{Response{Code: 502, Msg: "Access denied"}}
+
+// Response that is malformed or not expected for the command
+type ProtocolError struct {
+	Line   string
+	Cmd    string
+	Expect []Expect
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("Protocol error. Received=%s (Cmd=%s Expected=%+v)", e.Line, e.Cmd, e.Expect)
+}
+
+// Command without the password of authinfo pass
+func redact(cmd string) string {
+	if strings.HasPrefix(strings.ToLower(cmd), "authinfo pass") {
+		return cmd[:len("authinfo pass")]
+	}
+	return cmd
+}
+
+const FAIL_NO_GROUP = "no_group"
+const FAIL_NO_ARTICLE = "no_article"
+const FAIL_POSTING = "posting_failed"
+const FAIL_NOT_WANTED = "not_wanted" // 435 IHAVE, 438 CHECK
+const FAIL_REJECTED = "rejected"     // 437 IHAVE, 439 TAKETHIS
+const FAIL_AUTH = "auth_rejected"
+const FAIL_DENIED = "access_denied"
+const FAIL_TEMPORARY = "temporary" // Other 4xx
+const FAIL_PERMANENT = "permanent" // Other 5xx
+const FAIL_PROTOCOL = "protocol"
+const FAIL_CAPABILITY = "capability" // Expected capability missing
+const FAIL_TIMEOUT = "timeout"
+const FAIL_NETWORK = "network"
+const FAIL_OTHER = "other"
+
+// Classified error for reports
+type Failure struct {
+	Class string // FAIL_*
+	Code  int    // NNTP response code, 0 if none
+	Cmd   string // Command that failed, if known
+	Msg   string
+}
+
+// Classify e by response code or transport error
+func NewFailure(e error) Failure {
+	f := Failure{Class: FAIL_OTHER, Msg: e.Error()}
+	var res *ResponseError
+	var proto *ProtocolError
+	var capErr *CapabilityError
+	var ne net.Error
+	switch {
+	case errors.As(e, &res):
+		f.Code = res.Code
+		f.Cmd = res.Cmd
+		switch {
+		case errors.Is(res, ERR_NO_GROUP):
+			f.Class = FAIL_NO_GROUP
+		case errors.Is(res, ERR_NO_ARTICLE):
+			f.Class = FAIL_NO_ARTICLE
+		case errors.Is(res, ERR_POSTING_FAILED):
+			f.Class = FAIL_POSTING
+		case errors.Is(res, ERR_NOT_WANTED), errors.Is(res, ERR_STREAM_NOT_WANTED):
+			f.Class = FAIL_NOT_WANTED
+		case errors.Is(res, ERR_TRANSFER_REJECTED), errors.Is(res, ERR_STREAM_REJECTED):
+			f.Class = FAIL_REJECTED
+		case errors.Is(res, ERR_AUTH_REJECTED):
+			f.Class = FAIL_AUTH
+		case errors.Is(res, ERR_ACCESS_DENIED):
+			f.Class = FAIL_DENIED
+		case res.Temporary():
+			f.Class = FAIL_TEMPORARY
+		default:
+			f.Class = FAIL_PERMANENT
+		}
+	case errors.As(e, &proto):
+		f.Class = FAIL_PROTOCOL
+		f.Cmd = proto.Cmd
+	case errors.As(e, &capErr):
+		f.Class = FAIL_CAPABILITY
+	case errors.Is(e, context.DeadlineExceeded):
+		f.Class = FAIL_TIMEOUT
+	case errors.As(e, &ne):
+		f.Class = FAIL_NETWORK
+		if ne.Timeout() {
+			f.Class = FAIL_TIMEOUT
+		}
+	case errors.Is(e, io.EOF), errors.Is(e, io.ErrUnexpectedEOF):
+		f.Class = FAIL_NETWORK
+	}
+	return f
+}
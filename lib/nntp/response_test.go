@@ -0,0 +1,78 @@
+package nntp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestParseResponse(t *testing.T) {
+	tests := map[string]Response{
+		"200 welcome":            Response{Code: 200, Msg: "welcome"},
+		"430":                    Response{Code: 430},
+		"281 Ok, welcome aboard": Response{Code: 281, Msg: "Ok, welcome aboard"},
+	}
+	for line, expect := range tests {
+		res, e := ParseResponse(line)
+		if e != nil {
+			t.Fatalf("Input(%s) %s", line, e)
+		}
+		if res != expect {
+			t.Fatalf("Input(%s) expect=%+v but got=%+v", line, expect, res)
+		}
+	}
+	for _, line := range []string{"", "20", "abc def", "2000 x", "099 low", "600 high"} {
+		var proto *ProtocolError
+		if _, e := ParseResponse(line); !errors.As(e, &proto) {
+			t.Fatalf("Input(%s) expect ProtocolError but got=%v", line, e)
+		}
+	}
+}
+
+func TestNewFailure(t *testing.T) {
+	auth := &ResponseError{Response{Code: 481, Msg: "rejected", Cmd: "authinfo pass"}}
+	if !errors.Is(auth, ERR_AUTH_REJECTED) || !errors.Is(auth, ERR_RANGE) || errors.Is(auth, ERR_NO_ARTICLE) {
+		t.Fatal("ResponseError must match by code")
+	}
+
+	tests := []struct {
+		Err   error
+		Class string
+		Code  int
+	}{
+		{&ResponseError{Response{Code: 411}}, FAIL_NO_GROUP, 411},
+		{&ResponseError{Response{Code: 430}}, FAIL_NO_ARTICLE, 430},
+		{fmt.Errorf("part 3: %w", &ResponseError{Response{Code: 441}}), FAIL_POSTING, 441},
+		{&ResponseError{Response{Code: 438}}, FAIL_NOT_WANTED, 438},
+		{&ResponseError{Response{Code: 437}}, FAIL_REJECTED, 437},
+		{auth, FAIL_AUTH, 481},
+		{&ResponseError{Response{Code: 502}}, FAIL_DENIED, 502},
+		{&ResponseError{Response{Code: 400}}, FAIL_TEMPORARY, 400},
+		{&ResponseError{Response{Code: 500}}, FAIL_PERMANENT, 500},
+		{&ProtocolError{Line: "bogus"}, FAIL_PROTOCOL, 0},
+		{&TimeoutError{Op: "read"}, FAIL_TIMEOUT, 0},
+		{context.DeadlineExceeded, FAIL_TIMEOUT, 0},
+		{io.ErrUnexpectedEOF, FAIL_NETWORK, 0},
+		{errors.New("disk full"), FAIL_OTHER, 0},
+	}
+	for _, test := range tests {
+		f := NewFailure(test.Err)
+		if f.Class != test.Class || f.Code != test.Code {
+			t.Fatalf("Input(%s) expect=%s/%d but got=%s/%d", test.Err, test.Class, test.Code, f.Class, f.Code)
+		}
+	}
+	if f := NewFailure(auth); f.Cmd != "authinfo pass" || f.Msg != "authinfo pass: 481 rejected" {
+		t.Fatalf("Failure of auth got=%+v", f)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	if cmd := redact("AUTHINFO PASS secret"); cmd != "AUTHINFO PASS" {
+		t.Fatalf("Password not removed, got=%s", cmd)
+	}
+	if cmd := redact("stat <a@b>"); cmd != "stat <a@b>" {
+		t.Fatalf("Command changed, got=%s", cmd)
+	}
+}
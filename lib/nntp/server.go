@@ -0,0 +1,46 @@
+package nntp
+
+// Server settings as read from config.json
+type Server struct {
+	Name    string // Label in results, defaults to Address
+	Address string // server:port
+	User    string
+	Pass    string
+	TLS     TLS
+	Conns   int // Parallel connections
+
+	Capabilities []string // Expected, missing ones fail the probe
+}
+
+// Fill defaults
+func (s Server) Normalize() Server {
+	if s.Name == "" {
+		s.Name = s.Address
+	}
+	if s.Conns <= 0 {
+		s.Conns = 1
+	}
+	return s
+}
+
+// Normalized servers, or one server of the Address, User, Pass,
+// TLS, Conns and Capabilities fields of older configs when
+// servers is empty.
+func Legacy(servers []Server, addr, user, pass string, tls TLS, conns int, caps []string) []Server {
+	if len(servers) == 0 {
+		servers = []Server{{
+			Address: addr,
+			User:    user,
+			Pass:    pass,
+			TLS:     tls,
+			Conns:   conns,
+
+			Capabilities: caps,
+		}}
+	}
+	out := make([]Server, len(servers))
+	for i, s := range servers {
+		out[i] = s.Normalize()
+	}
+	return out
+}
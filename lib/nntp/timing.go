@@ -0,0 +1,24 @@
+package nntp
+
+import (
+	"time"
+)
+
+// Timestamps of the last command, zero when not reached.
+type Timing struct {
+	Sent       time.Time // Command flushed
+	Status     time.Time // First response line after Sent
+	FirstByte  time.Time // First byte of the multi-line block
+	Terminator time.Time // End of the multi-line block, read or written
+}
+
+// Timings of a single connection in ms
+type ConnPerf struct {
+	Name  string
+	Conn  float64
+	TLS   float64
+	Auth  float64
+	Arts  int
+	Bytes int64
+	KBsec float64
+}
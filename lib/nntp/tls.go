@@ -0,0 +1,87 @@
+package nntp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+const TLS_NONE = ""             // Plaintext (default)
+const TLS_IMPLICIT = "implicit" // TLS before welcome (port 563)
+const TLS_STARTTLS = "starttls" // RFC4642 upgrade after welcome (port 119)
+
+// TLS settings as read from config.json
+type TLS struct {
+	Mode       string // TLS_NONE, TLS_IMPLICIT or TLS_STARTTLS
+	CAFile     string // PEM bundle, empty uses system roots
+	ServerName string // SNI, empty uses host from address
+	Insecure   bool   // Skip certificate verification
+}
+
+// SetTLS configures transport security, call before Init.
+func (c *Client) SetTLS(t TLS) error {
+	if t.Mode == TLS_NONE {
+		c.tlsMode = TLS_NONE
+		c.tls = nil
+		return nil
+	}
+	if t.Mode != TLS_IMPLICIT && t.Mode != TLS_STARTTLS {
+		return fmt.Errorf("Invalid TLS mode: %s", t.Mode)
+	}
+
+	cfg := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.Insecure,
+	}
+	if cfg.ServerName == "" {
+		host, _, e := net.SplitHostPort(c.listen)
+		if e != nil {
+			return e
+		}
+		cfg.ServerName = host
+	}
+	if t.CAFile != "" {
+		pem, e := ioutil.ReadFile(t.CAFile)
+		if e != nil {
+			return e
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("No certificates in CAFile: " + t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	c.tlsMode = t.Mode
+	c.tls = cfg
+	return nil
+}
+
+// Wrap conn in TLS and remember how long the handshake took
+func (c *Client) handshake(ctx context.Context, conn net.Conn) (net.Conn, error) {
+	begin := time.Now()
+	tc := tls.Client(conn, c.tls)
+	if e := tc.HandshakeContext(ctx); e != nil {
+		return nil, e
+	}
+	c.TLSTime = time.Now().Sub(begin)
+	return tc, nil
+}
+
+// Upgrade plaintext connection with RFC4642 STARTTLS
+func (c *Client) startTLS(ctx context.Context) error {
+	if _, e := c.SendContext(ctx, "STARTTLS", []Expect{Expect{"382 ", false}}); e != nil {
+		return e
+	}
+	conn, e := c.handshake(ctx, c.conn)
+	if e != nil {
+		return e
+	}
+	c.setConn(conn)
+	return nil
+}
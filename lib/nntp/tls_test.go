@@ -0,0 +1,154 @@
+package nntp
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Self-signed certificate for 127.0.0.1 and its PEM in a file
+// for TLS.CAFile.
+func certificate(t *testing.T) (tls.Certificate, string) {
+	key, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if e != nil {
+		t.Fatal(e)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+
+		BasicConstraintsValid: true,
+	}
+	der, e := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	f, e := ioutil.TempFile("", "ca*.pem")
+	if e != nil {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	if e := pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: der}); e != nil {
+		t.Fatal(e)
+	}
+	if e := f.Close(); e != nil {
+		t.Fatal(e)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, f.Name()
+}
+
+// Server answering STARTTLS with starttls, upgrading on 382,
+// and DATE with 111 afterwards.
+func tlsServer(t *testing.T, cert tls.Certificate, implicit bool, starttls string) string {
+	l, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+	go func() {
+		defer l.Close()
+		conn, e := l.Accept()
+		if e != nil {
+			return
+		}
+		if implicit {
+			conn = tls.Server(conn, cfg)
+		}
+		defer func() { conn.Close() }()
+		r := bufio.NewReader(conn)
+		conn.Write([]byte("200 welcome\r\n"))
+		for {
+			line, e := r.ReadString('\n')
+			if e != nil {
+				return
+			}
+			switch strings.TrimSpace(line) {
+			case "STARTTLS":
+				conn.Write([]byte(starttls))
+				if strings.HasPrefix(starttls, "382 ") {
+					conn = tls.Server(conn, cfg)
+					r = bufio.NewReader(conn)
+				}
+			case "DATE":
+				if _, ok := conn.(*tls.Conn); !ok {
+					conn.Write([]byte("483 encryption required\r\n"))
+					continue
+				}
+				conn.Write([]byte("111 20260101000000\r\n"))
+			default:
+				conn.Write([]byte("500 unknown command\r\n"))
+			}
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestStartTLS(t *testing.T) {
+	cert, ca := certificate(t)
+	for _, mode := range []string{TLS_STARTTLS, TLS_IMPLICIT} {
+		c := New(tlsServer(t, cert, mode == TLS_IMPLICIT, "382 continue with TLS negotiation\r\n"), "test", false)
+		if e := c.SetTLS(TLS{Mode: mode, CAFile: ca}); e != nil {
+			t.Fatal(e)
+		}
+		if e := c.InitContext(context.Background()); e != nil {
+			t.Fatalf("%s: %s", mode, e)
+		}
+		if c.TLSTime <= 0 {
+			t.Fatalf("%s: TLSTime not set", mode)
+		}
+		if _, e := c.Send("DATE", []Expect{Expect{"111 ", false}}); e != nil {
+			t.Fatalf("%s: DATE after handshake %s", mode, e)
+		}
+		c.Close()
+	}
+}
+
+func TestStartTLSRefused(t *testing.T) {
+	cert, ca := certificate(t)
+	c := New(tlsServer(t, cert, false, "580 can not initiate TLS negotiation\r\n"), "test", false)
+	if e := c.SetTLS(TLS{Mode: TLS_STARTTLS, CAFile: ca}); e != nil {
+		t.Fatal(e)
+	}
+	e := c.InitContext(context.Background())
+	defer c.Close()
+	var res *ResponseError
+	if !errors.As(e, &res) || res.Code != 580 {
+		t.Fatalf("Expect 580 but got=%v", e)
+	}
+	if c.TLSTime != 0 {
+		t.Fatalf("TLSTime expect=0 but got=%s", c.TLSTime)
+	}
+}
+
+func TestTLSUnknownCA(t *testing.T) {
+	cert, _ := certificate(t)
+	_, other := certificate(t)
+	c := New(tlsServer(t, cert, true, ""), "test", false)
+	if e := c.SetTLS(TLS{Mode: TLS_IMPLICIT, CAFile: other}); e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+	var unknown x509.UnknownAuthorityError
+	if e := c.InitContext(context.Background()); !errors.As(e, &unknown) {
+		t.Fatalf("Expect UnknownAuthorityError but got=%v", e)
+	}
+}
@@ -1,67 +1,129 @@
+// NZB 1.1 model, see http://www.newzbin.com/DTD/nzb/nzb-1.1.dtd
 package nzb
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
-	"sort"
+	"io/ioutil"
+	"strconv"
+	"time"
 )
 
+const XMLNS = "http://www.newzbin.com/DTD/2003/nzb"
+const DOCTYPE = `<!DOCTYPE nzb PUBLIC "-//newzBin//DTD NZB 1.1//EN" "http://www.newzbin.com/DTD/nzb/nzb-1.1.dtd">`
+
 type Msg struct {
 	Msgid string
 	Size  int64
 }
 
+type Nzb struct {
+	XMLName xml.Name `xml:"http://www.newzbin.com/DTD/2003/nzb nzb"`
+	Meta    []Meta   `xml:"head>meta"`
+	Files   []File   `xml:"file"`
+}
+
+type Meta struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type File struct {
+	Poster   string    `xml:"poster,attr"`
+	Date     Date      `xml:"date,attr"`
+	Subject  string    `xml:"subject,attr"`
+	Groups   []string  `xml:"groups>group"`
+	Segments []Segment `xml:"segments>segment"`
+}
+
 type Segment struct {
 	Bytes  int64  `xml:"bytes,attr"`
 	Number int    `xml:"number,attr"`
-	Msgid  string `xml:",innerxml"`
+	Msgid  string `xml:",chardata"`
 }
-type Nzb struct {
-	File struct {
-		Segments struct {
-			Segment []Segment `xml:"segment"`
-		} `xml:"segments"`
-	} `xml:"file"`
+
+// Unix timestamp as NZB 1.1 demands, older
+// uploads wrote RFC822 so accept that on read.
+type Date struct {
+	time.Time
 }
 
-func segments(msgids []Msg) string {
-	// Get keys and sort
-	var keys []int
-	for idx, _ := range msgids {
-		keys = append(keys, idx)
+func (d Date) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: strconv.FormatInt(d.Unix(), 10)}, nil
+}
+
+func (d *Date) UnmarshalXMLAttr(attr xml.Attr) error {
+	if unix, e := strconv.ParseInt(attr.Value, 10, 64); e == nil {
+		d.Time = time.Unix(unix, 0)
+		return nil
+	}
+	t, e := time.Parse(time.RFC822, attr.Value)
+	if e != nil {
+		return fmt.Errorf("Invalid NZB date: %s", attr.Value)
 	}
-	sort.Ints(keys)
+	d.Time = t
+	return nil
+}
 
-	// <segment bytes="394827" number="1">Part1of87.CC19C709AFA241E5A8820BA44725CCE0@1444933554.local</segment>
-	segments := ""
-	for _, idx := range keys {
-		msg := msgids[idx]
-		segments += fmt.Sprintf(`<segment bytes="%d" number="%d">%s</segment>`, msg.Size, 1+idx, msg.Msgid)
+// Number msgids in given order
+func Segments(msgids []Msg) []Segment {
+	segments := make([]Segment, 0, len(msgids))
+	for idx, msg := range msgids {
+		segments = append(segments, Segment{
+			Bytes:  msg.Size,
+			Number: 1 + idx,
+			Msgid:  msg.Msgid,
+		})
 	}
 	return segments
 }
 
-func Build(subject string, msgids []Msg, date string) string {
-	segments := segments(msgids)
-
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" ?>
-<!DOCTYPE nzb PUBLIC "-//newzBin//DTD NZB 1.0//EN" "http://www.nzbindex.com/nzb-1.0.dtd">
-<!-- NZB Generated by UF Upload -->
-<nzb xmlns="http://www.newzbin.com/DTD/2003/nzb">
-<file poster="support@usenet.farm" date="%s" subject="%s">
-<groups>
-<group>alt.binaries.test</group>
-</groups>
-<segments>%s</segments>
-</file>
-</nzb>`, date, subject, segments)
+// Value of first meta with given type
+func (n *Nzb) MetaValue(typ string) string {
+	for _, m := range n.Meta {
+		if m.Type == typ {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// Segments of all files in order
+func (n *Nzb) Segments() []Segment {
+	var segments []Segment
+	for _, f := range n.Files {
+		segments = append(segments, f.Segments...)
+	}
+	return segments
+}
+
+func Marshal(n *Nzb) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteString(xml.Header)
+	buf.WriteString(DOCTYPE + "\n")
+
+	enc := xml.NewEncoder(buf)
+	enc.Indent("", "\t")
+	if e := enc.Encode(n); e != nil {
+		return nil, e
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+func Unmarshal(b []byte, n *Nzb) error {
+	return xml.Unmarshal(b, n)
 }
 
 func Read(f io.Reader) (Nzb, error) {
 	n := Nzb{}
-	dec := xml.NewDecoder(f)
-	if e := dec.Decode(&n); e != nil {
+	b, e := ioutil.ReadAll(f)
+	if e != nil {
+		return n, e
+	}
+	if e := Unmarshal(b, &n); e != nil {
 		return n, e
 	}
 	return n, nil
@@ -7,66 +7,127 @@ import (
 	"time"
 )
 
-func TestCount(t *testing.T) {
-	now := time.Now().String()
-	// Build(subject string, msgids map[string]int64) string
-	a := Build("test", []Msg{
-		Msg{"a@test", 10},
-		Msg{"b@test", 200},
-		Msg{"c@test", 3000},
-	}, now)
-	example := `<?xml version="1.0" encoding="UTF-8" ?>
-<!DOCTYPE nzb PUBLIC "-//newzBin//DTD NZB 1.0//EN" "http://www.nzbindex.com/nzb-1.0.dtd">
-<!-- NZB Generated by UF Upload -->
+func TestMarshal(t *testing.T) {
+	n := &Nzb{
+		Meta: []Meta{Meta{Type: "title", Value: "sla-2016-02-29.zip"}},
+		Files: []File{File{
+			Poster:  "support@usenet.farm",
+			Date:    Date{Time: time.Unix(1456704000, 0)},
+			Subject: "test",
+			Groups:  []string{"alt.binaries.test"},
+			Segments: Segments([]Msg{
+				Msg{"a@test", 10},
+				Msg{"b@test", 200},
+				Msg{"c@test", 3000},
+			}),
+		}},
+	}
+	a, e := Marshal(n)
+	if e != nil {
+		t.Fatal(e)
+	}
+	example := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE nzb PUBLIC "-//newzBin//DTD NZB 1.1//EN" "http://www.newzbin.com/DTD/nzb/nzb-1.1.dtd">
 <nzb xmlns="http://www.newzbin.com/DTD/2003/nzb">
-<file poster="support@usenet.farm" date="::date::" subject="test">
-<groups>
-<group>alt.binaries.test</group>
-</groups>
-<segments><segment bytes="10" number="1">a@test</segment><segment bytes="200" number="2">b@test</segment><segment bytes="3000" number="3">c@test</segment></segments>
-</file>
-</nzb>`
-	example = strings.Replace(example, "::date::", now, 1)
+	<head>
+		<meta type="title">sla-2016-02-29.zip</meta>
+	</head>
+	<file poster="support@usenet.farm" date="1456704000" subject="test">
+		<groups>
+			<group>alt.binaries.test</group>
+		</groups>
+		<segments>
+			<segment bytes="10" number="1">a@test</segment>
+			<segment bytes="200" number="2">b@test</segment>
+			<segment bytes="3000" number="3">c@test</segment>
+		</segments>
+	</file>
+</nzb>
+`
 
-	if a != example {
+	if string(a) != example {
 		fmt.Printf("GEN=\n%s\n\nHARDCODED=\n%s\n", a, example)
 		t.Errorf("NZB body does not match (%d/%d)", len(a), len(example))
 	}
 }
 
 func TestSorting(t *testing.T) {
-	now := time.Now().String()
-	// Build(subject string, msgids map[string]int64) string
-	a := Build("test", []Msg{
+	segments := Segments([]Msg{
 		Msg{"c@test", 3000},
 		Msg{"a@test", 10},
 		Msg{"b@test", 200},
-	}, now)
-	example := `<?xml version="1.0" encoding="UTF-8" ?>
-<!DOCTYPE nzb PUBLIC "-//newzBin//DTD NZB 1.0//EN" "http://www.nzbindex.com/nzb-1.0.dtd">
-<!-- NZB Generated by UF Upload -->
-<nzb xmlns="http://www.newzbin.com/DTD/2003/nzb">
-<file poster="support@usenet.farm" date="::date::" subject="test">
-<groups>
-<group>alt.binaries.test</group>
-</groups>
-<segments><segment bytes="3000" number="1">c@test</segment><segment bytes="10" number="2">a@test</segment><segment bytes="200" number="3">b@test</segment></segments>
-</file>
-</nzb>`
-	example = strings.Replace(example, "::date::", now, 1)
+	})
+	expect := []Segment{
+		Segment{Bytes: 3000, Number: 1, Msgid: "c@test"},
+		Segment{Bytes: 10, Number: 2, Msgid: "a@test"},
+		Segment{Bytes: 200, Number: 3, Msgid: "b@test"},
+	}
+	for idx, segment := range segments {
+		if segment != expect[idx] {
+			t.Errorf("Segment mismatch. expect=%+v, found=%+v", expect[idx], segment)
+		}
+	}
+}
 
-	if a != example {
-		fmt.Printf("GEN=\n%s\n\nHARDCODED=\n%s\n", a, example)
-		t.Errorf("NZB body does not match (%d/%d)", len(a), len(example))
+func TestRoundTrip(t *testing.T) {
+	in := &Nzb{
+		Meta: []Meta{
+			Meta{Type: "title", Value: `Q&A <"quoted">`},
+			Meta{Type: "x-sha256", Value: "abc"},
+		},
+		Files: []File{
+			File{
+				Poster:   `Usenet.Farm <support@usenet.farm>`,
+				Date:     Date{Time: time.Unix(1456704000, 0)},
+				Subject:  `"a" & 'b' (1/2)`,
+				Groups:   []string{"alt.binaries.test", "alt.binaries.misc"},
+				Segments: Segments([]Msg{Msg{"a&b@test", 10}}),
+			},
+			File{
+				Poster:   "support@usenet.farm",
+				Date:     Date{Time: time.Unix(1456704001, 0)},
+				Subject:  "second",
+				Groups:   []string{"alt.binaries.test"},
+				Segments: Segments([]Msg{Msg{"c@test", 20}, Msg{"d@test", 30}}),
+			},
+		},
+	}
+	b, e := Marshal(in)
+	if e != nil {
+		t.Fatal(e)
+	}
+	out := &Nzb{}
+	if e := Unmarshal(b, out); e != nil {
+		t.Fatal(e)
+	}
+
+	if out.MetaValue("title") != in.Meta[0].Value {
+		t.Errorf("Meta mismatch. expect=%s, found=%s", in.Meta[0].Value, out.MetaValue("title"))
+	}
+	if len(out.Files) != len(in.Files) {
+		t.Fatalf("Files mismatch. expect=%d, found=%d", len(in.Files), len(out.Files))
+	}
+	for idx, f := range out.Files {
+		expect := in.Files[idx]
+		if f.Poster != expect.Poster || f.Subject != expect.Subject || !f.Date.Equal(expect.Date.Time) {
+			t.Errorf("File mismatch. expect=%+v, found=%+v", expect, f)
+		}
+		if strings.Join(f.Groups, ",") != strings.Join(expect.Groups, ",") {
+			t.Errorf("Groups mismatch. expect=%v, found=%v", expect.Groups, f.Groups)
+		}
+	}
+	if len(out.Segments()) != 3 || out.Segments()[0].Msgid != "a&b@test" {
+		t.Errorf("Segments mismatch. found=%+v", out.Segments())
 	}
 }
 
 func TestRead(t *testing.T) {
+	// Format as written by older versions of upload
 	in := `<?xml version="1.0" encoding="UTF-8" ?>
 <!DOCTYPE nzb PUBLIC "-//newzBin//DTD NZB 1.0//EN" "http://www.nzbindex.com/nzb-1.0.dtd">
 <!-- NZB Generated by UF Upload -->
 <nzb xmlns="http://www.newzbin.com/DTD/2003/nzb">
-<file poster="support@usenet.farm" date="::date::" subject="test">
+<file poster="support@usenet.farm" date="29 Feb 16 10:00 UTC" subject="test">
 <groups>
 <group>alt.binaries.test</group>
 </groups>
@@ -77,8 +138,14 @@ func TestRead(t *testing.T) {
 	if e != nil {
 		panic(e)
 	}
-	if len(nzb.File.Segments.Segment) != 3 {
-		t.Errorf("NZB contains 3 segment but found=%d", len(nzb.File.Segments.Segment))
+	if len(nzb.Files) != 1 {
+		t.Fatalf("NZB contains 1 file but found=%d", len(nzb.Files))
+	}
+	if nzb.Files[0].Date.Format("2006-01-02") != "2016-02-29" {
+		t.Errorf("Date mismatch. expect=2016-02-29, found=%s", nzb.Files[0].Date)
+	}
+	if len(nzb.Files[0].Segments) != 3 {
+		t.Errorf("NZB contains 3 segment but found=%d", len(nzb.Files[0].Segments))
 	}
 
 	expect := []Segment{
@@ -86,15 +153,15 @@ func TestRead(t *testing.T) {
 		Segment{Bytes: 10, Number: 2, Msgid: "a@test"},
 		Segment{Bytes: 200, Number: 3, Msgid: "b@test"},
 	}
-	for idx, segment := range nzb.File.Segments.Segment {
+	for idx, segment := range nzb.Files[0].Segments {
 		if segment.Bytes != expect[idx].Bytes {
-			t.Errorf("Bytes mismatch. expect=%d, found=%d", segment.Bytes, expect[idx].Bytes)
+			t.Errorf("Bytes mismatch. expect=%d, found=%d", expect[idx].Bytes, segment.Bytes)
 		}
 		if segment.Number != expect[idx].Number {
-			t.Errorf("Number mismatch. expect=%d, found=%d", segment.Number, expect[idx].Number)
+			t.Errorf("Number mismatch. expect=%d, found=%d", expect[idx].Number, segment.Number)
 		}
 		if segment.Msgid != expect[idx].Msgid {
-			t.Errorf("Msgid mismatch. expect=%d, found=%d", segment.Msgid, expect[idx].Msgid)
+			t.Errorf("Msgid mismatch. expect=%s, found=%s", expect[idx].Msgid, segment.Msgid)
 		}
 	}
-}
\ No newline at end of file
+}
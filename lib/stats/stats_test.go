@@ -0,0 +1,58 @@
+package stats
+
+import (
+	"testing"
+)
+
+func TestMean(t *testing.T) {
+	if m := Mean(nil); m != 0 {
+		t.Errorf("Mean of nothing should be 0, found=%f", m)
+	}
+	if m := Mean([]float64{1, 2, 3, 4}); m != 2.5 {
+		t.Errorf("Mean mismatch. expect=2.5, found=%f", m)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{50, 10, 40, 20, 30}
+	tests := []struct {
+		P      float64
+		Expect float64
+	}{
+		{0, 10},
+		{50, 30},
+		{90, 46},
+		{99, 49.6},
+		{100, 50},
+	}
+	for _, test := range tests {
+		if v := Percentile(values, test.P); v < test.Expect-1e-9 || v > test.Expect+1e-9 {
+			t.Errorf("p%.0f mismatch. expect=%f, found=%f", test.P, test.Expect, v)
+		}
+	}
+	if values[0] != 50 {
+		t.Errorf("Percentile modified the input: %v", values)
+	}
+	if v := Percentile([]float64{7}, 99); v != 7 {
+		t.Errorf("Single value mismatch. expect=7, found=%f", v)
+	}
+	if v := Percentile(nil, 50); v != 0 {
+		t.Errorf("Percentile of nothing should be 0, found=%f", v)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	expect := Summary{Count: 8, Min: 2, Max: 9, Mean: 5, StdDev: 2, P50: 4.5, P90: 7.6, P99: 8.86}
+	diff := func(a, b float64) bool {
+		return a < b-1e-9 || a > b+1e-9
+	}
+	if s.Count != expect.Count || diff(s.Min, expect.Min) || diff(s.Max, expect.Max) ||
+		diff(s.Mean, expect.Mean) || diff(s.StdDev, expect.StdDev) || diff(s.P50, expect.P50) ||
+		diff(s.P90, expect.P90) || diff(s.P99, expect.P99) {
+		t.Errorf("Summary mismatch.\nexpect=%+v\nfound=%+v", expect, s)
+	}
+	if s := Summarize(nil); s != (Summary{}) {
+		t.Errorf("Summary of nothing should be empty, found=%+v", s)
+	}
+}
@@ -0,0 +1,34 @@
+package upload
+
+import (
+	"sla/lib/metrics"
+	"time"
+)
+
+// Add perf of one run against server to m
+func Observe(m *metrics.SLA, server string, perf Perf) {
+	l := metrics.Label(server, "upload")
+
+	for _, c := range perf.Conns {
+		m.Connect.Observe(l, c.Conn/1000)
+		m.TLS.Observe(l, c.TLS/1000)
+		m.Auth.Observe(l, c.Auth/1000)
+	}
+	for _, art := range perf.Arts {
+		m.Article.Observe(l, art.Time/1000)
+		m.Throughput.Observe(l, art.Speed*1024)
+	}
+	m.Speed.Set(l, perf.TotalKBsec*1024)
+	m.Articles.Add(metrics.Label(server, "upload", "state", "posted"), float64(len(perf.Arts)))
+	for _, prop := range perf.Propagation {
+		pl := metrics.Label(prop.Name, "upload", "source", server)
+		for _, ms := range prop.Delays {
+			if ms >= 0 {
+				m.Propagate.Observe(pl, ms/1000)
+			}
+		}
+		m.Errors.Add(pl, float64(len(prop.Error)))
+	}
+	m.Errors.Add(l, float64(len(perf.Error)))
+	m.Timestamp.Set(l, float64(time.Now().Unix()))
+}
@@ -0,0 +1,224 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sla/lib/duration"
+	"sla/lib/nntp"
+	"time"
+)
+
+type job struct {
+	Idx   int           // Position in NZB
+	Msgid string        //
+	Body  *bytes.Buffer // Headers+yEnc part
+	Size  int64         // yEnc part without headers
+}
+
+type result struct {
+	Idx   int
+	Perf  ArtPerf
+	Begin time.Time
+	End   time.Time
+}
+
+// Post articles from jobs over one connection
+type poster struct {
+	Verbose bool
+	Mode    string // MODE_POST, MODE_IHAVE or MODE_STREAM
+	Perf    nntp.ConnPerf
+
+	conn *nntp.Client
+	prop *propagator   // Peers to poll for posted articles
+	busy time.Duration // Time spent on articles
+}
+
+func newPoster(c Config, s nntp.Server, id int, mode string, verbose bool) (*poster, error) {
+	name := fmt.Sprintf("%d", id)
+	conn, e := dial(c, s, name, verbose)
+	if e != nil {
+		return nil, e
+	}
+
+	return &poster{
+		Verbose: verbose,
+		Mode:    mode,
+		Perf:    nntp.ConnPerf{Name: name},
+		conn:    conn,
+	}, nil
+}
+
+// Connect and authenticate while timing both
+func (p *poster) Connect(ctx context.Context, user, pass string) error {
+	perfBegin := time.Now()
+	if e := p.conn.InitContext(ctx); e != nil {
+		return e
+	}
+	perfInit := time.Now()
+	if e := p.conn.AuthContext(ctx, user, pass); e != nil {
+		return e
+	}
+	perfAuth := time.Now()
+
+	if p.Mode == MODE_POST {
+		if _, e := p.conn.CapabilitiesContext(ctx); e != nil {
+			return e
+		}
+	} else {
+		// Transit servers drop IHAVE in reader mode
+		if _, e := p.conn.FeedCapabilitiesContext(ctx); e != nil {
+			return e
+		}
+	}
+	if p.Mode == MODE_STREAM {
+		if e := p.conn.ModeStreamContext(ctx); e != nil {
+			return e
+		}
+	}
+
+	p.Perf.Conn = duration.MilliSeconds(perfInit.Sub(perfBegin) - p.conn.TLSTime)
+	p.Perf.TLS = duration.MilliSeconds(p.conn.TLSTime)
+	p.Perf.Auth = duration.MilliSeconds(perfAuth.Sub(perfInit))
+	return nil
+}
+
+// Post until jobs is drained or ctx is done, so an upload that
+// stops encoding before closing jobs releases every poster.
+func (p *poster) Run(ctx context.Context, jobs <-chan job, results chan<- result) error {
+	for {
+		var j job
+		var ok bool
+		select {
+		case j, ok = <-jobs:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if !ok {
+			break
+		}
+		if e := ctx.Err(); e != nil {
+			return e
+		}
+		begin := time.Now()
+		t, e := p.post(ctx, j)
+		if e != nil {
+			return e
+		}
+		end := time.Now()
+		d := end.Sub(begin)
+		p.prop.Posted(j.Idx, j.Msgid, end)
+
+		if p.Verbose {
+			fmt.Println(fmt.Sprintf(
+				"C(%s) Posted %s in %s",
+				p.Perf.Name, j.Msgid, d.String(),
+			))
+		}
+
+		kbSec := float64(0)
+		if d > 0 {
+			kbSec = float64(j.Size/1024) / d.Seconds()
+		}
+		p.busy += d
+		p.Perf.Arts++
+		p.Perf.Bytes += j.Size
+		results <- result{
+			Idx: j.Idx,
+			Perf: ArtPerf{
+				MsgId:    j.Msgid,
+				Conn:     p.Perf.Name,
+				Time:     duration.MilliSeconds(d),
+				TTFB:     duration.Between(t.Sent, t.Status),
+				Transfer: duration.Between(t.Status, t.Terminator),
+				Ack:      duration.Between(t.Terminator, end),
+				Size:     j.Size,
+				Speed:    kbSec,     // kb/sec
+				BitSpeed: kbSec * 8, // kbit/sec
+			},
+			Begin: begin,
+			End:   end,
+		}
+	}
+
+	if p.busy > 0 {
+		p.Perf.KBsec = float64(p.Perf.Bytes/1024) / p.busy.Seconds()
+	}
+	return nil
+}
+
+// Offer and write j with the command of p.Mode, the timing
+// spans from the offer until the article is accepted.
+func (p *poster) post(ctx context.Context, j job) (nntp.Timing, error) {
+	var e error
+	switch p.Mode {
+	case MODE_IHAVE:
+		e = p.ihave(ctx, j)
+	case MODE_STREAM:
+		return p.stream(ctx, j)
+	default:
+		e = p.postArticle(ctx, j)
+	}
+	return p.conn.Timing, e
+}
+
+func (p *poster) postArticle(ctx context.Context, j job) error {
+	if e := p.conn.PostContext(ctx); e != nil {
+		return e
+	}
+	if e := p.write(ctx, j); e != nil {
+		return e
+	}
+	return p.conn.PostCloseContext(ctx)
+}
+
+func (p *poster) ihave(ctx context.Context, j job) error {
+	ok, e := p.conn.IHaveContext(ctx, j.Msgid)
+	if e != nil {
+		return e
+	}
+	if !ok {
+		return &nntp.ResponseError{Response: p.conn.Response}
+	}
+	if e := p.write(ctx, j); e != nil {
+		return e
+	}
+	return p.conn.IHaveCloseContext(ctx)
+}
+
+// CHECK and TAKETHIS, the timing of CHECK (lookup) is merged
+// with the terminator of TAKETHIS.
+func (p *poster) stream(ctx context.Context, j job) (nntp.Timing, error) {
+	ok, e := p.conn.CheckContext(ctx, j.Msgid)
+	if e != nil {
+		return nntp.Timing{}, e
+	}
+	if !ok {
+		return nntp.Timing{}, &nntp.ResponseError{Response: p.conn.Response}
+	}
+	t := p.conn.Timing
+	if e := p.conn.TakeThisContext(ctx, j.Msgid); e != nil {
+		return nntp.Timing{}, e
+	}
+	if e := p.write(ctx, j); e != nil {
+		return nntp.Timing{}, e
+	}
+	if e := p.conn.TakeThisCloseContext(ctx); e != nil {
+		return nntp.Timing{}, e
+	}
+	t.Terminator = p.conn.Timing.Terminator
+	return t, nil
+}
+
+// Headers and yEnc part of j as dot-stuffed block
+func (p *poster) write(ctx context.Context, j job) error {
+	w := p.conn.GetDotWriterContext(ctx)
+	if _, e := j.Body.WriteTo(w); e != nil {
+		return e
+	}
+	return w.Close()
+}
+
+func (p *poster) Close() error {
+	return p.conn.Close()
+}
@@ -0,0 +1,217 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"sla/lib/duration"
+	"sla/lib/nntp"
+	"time"
+)
+
+// Upper bounds in ms of PropagationPerf.Histogram
+var PROPAGATION_BUCKETS = []float64{100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 300000}
+
+// Polling of the servers after the first
+type PropagationConfig struct {
+	Interval duration.Duration // Between STAT rounds, default 1s
+	Timeout  duration.Duration // Give up after the last part is posted, default 5m
+}
+
+// Count of articles that appeared within Le ms (and above
+// the previous bucket), Le is -1 for slower than all buckets
+type Bucket struct {
+	Le    float64
+	Count int
+}
+
+// Delay until the posted articles appeared on one peer,
+// measured from the 240 of POST to the first 223 of STAT.
+// Polling runs alongside the upload, every part is polled
+// from its own 240 on.
+type PropagationPerf struct {
+	Name      string
+	Checked   int       // Articles polled
+	Found     int       // Appeared before Timeout
+	Delays    []float64 // ms per part, -1 if it never appeared
+	Max       float64   // ms until the last article appeared
+	Histogram []Bucket
+	Error     []string
+}
+
+// Connection for one server with the timeouts of c
+func dial(c Config, s nntp.Server, name string, verbose bool) (*nntp.Client, error) {
+	conn := nntp.New(s.Address, name, verbose)
+	if e := conn.SetTLS(s.TLS); e != nil {
+		return nil, e
+	}
+	conn.DialTimeout = c.DialTimeout.Duration
+	conn.ReadTimeout = c.ReadTimeout.Duration
+	conn.WriteTimeout = c.WriteTimeout.Duration
+	return conn, nil
+}
+
+// Part to poll, At is its 240
+type posting struct {
+	Idx   int
+	Msgid string
+	At    time.Time
+}
+
+// Pollers of every peer, fed with each part once it is posted
+type propagator struct {
+	feeds []chan posting
+	out   []PropagationPerf
+	done  chan struct{}
+}
+
+// Poll every peer in parallel for the count parts passed to
+// Posted, the pollers stop when ctx is done.
+func startPropagation(ctx context.Context, c Config, peers []nntp.Server, count int, verbose bool) *propagator {
+	p := &propagator{
+		out:  make([]PropagationPerf, len(peers)),
+		done: make(chan struct{}, len(peers)),
+	}
+	for i, s := range peers {
+		// Room for every part so posters never wait
+		feed := make(chan posting, count)
+		p.feeds = append(p.feeds, feed)
+		go func(i int, s nntp.Server) {
+			p.out[i] = poll(ctx, c, s, count, feed, verbose)
+			p.done <- struct{}{}
+		}(i, s)
+	}
+	return p
+}
+
+// Queue the part for every peer
+func (p *propagator) Posted(idx int, msgid string, at time.Time) {
+	for _, feed := range p.feeds {
+		feed <- posting{Idx: idx, Msgid: msgid, At: at}
+	}
+}
+
+// Every part is posted, wait until the peers found them all
+// or gave up.
+func (p *propagator) Wait() []PropagationPerf {
+	for _, feed := range p.feeds {
+		close(feed)
+	}
+	for range p.feeds {
+		<-p.done
+	}
+	return p.out
+}
+
+func poll(ctx context.Context, c Config, s nntp.Server, count int, feed <-chan posting, verbose bool) PropagationPerf {
+	perf := PropagationPerf{
+		Name:      s.Name,
+		Delays:    make([]float64, count),
+		Histogram: make([]Bucket, len(PROPAGATION_BUCKETS)+1),
+		Error:     []string{},
+	}
+	for i := range perf.Delays {
+		perf.Delays[i] = -1
+	}
+	for i, le := range PROPAGATION_BUCKETS {
+		perf.Histogram[i].Le = le
+	}
+	perf.Histogram[len(PROPAGATION_BUCKETS)].Le = -1
+
+	interval := c.Propagation.Interval.Duration
+	if interval <= 0 {
+		interval = time.Second
+	}
+	timeout := c.Propagation.Timeout.Duration
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+
+	conn, e := dial(c, s, s.Name, verbose)
+	if e != nil {
+		perf.Error = append(perf.Error, e.Error())
+		return perf
+	}
+	defer conn.Close()
+	if e := conn.InitContext(ctx); e != nil {
+		perf.Error = append(perf.Error, e.Error())
+		return perf
+	}
+	if e := conn.AuthContext(ctx, s.User, s.Pass); e != nil {
+		perf.Error = append(perf.Error, e.Error())
+		return perf
+	}
+	// STAT needs reader mode
+	if _, e := conn.CapabilitiesContext(ctx); e != nil {
+		perf.Error = append(perf.Error, e.Error())
+		return perf
+	}
+
+	var pending []posting
+	var expire <-chan time.Time // Timeout once every part is posted
+	for {
+		// Parts posted since the last round
+	queue:
+		for feed != nil {
+			select {
+			case art, ok := <-feed:
+				if !ok {
+					feed = nil
+					expire = time.After(timeout)
+					break queue
+				}
+				pending = append(pending, art)
+				perf.Checked++
+			default:
+				break queue
+			}
+		}
+
+		var missing []posting
+		for _, art := range pending {
+			found, e := conn.StatContext(ctx, art.Msgid)
+			if e != nil && ctx.Err() != nil {
+				// Upload stopped while polling
+				break
+			}
+			if e != nil {
+				perf.Error = append(perf.Error, e.Error())
+				return perf
+			}
+			if !found {
+				missing = append(missing, art)
+				continue
+			}
+			delay := duration.MilliSeconds(time.Since(art.At))
+			perf.Delays[art.Idx] = delay
+			perf.Found++
+			if delay > perf.Max {
+				perf.Max = delay
+			}
+			bucket := len(PROPAGATION_BUCKETS)
+			for i, le := range PROPAGATION_BUCKETS {
+				if delay <= le {
+					bucket = i
+					break
+				}
+			}
+			perf.Histogram[bucket].Count++
+		}
+		pending = missing
+		if verbose {
+			fmt.Printf("P(%s) %d/%d articles propagated\n", s.Name, perf.Found, perf.Checked)
+		}
+		if feed == nil && perf.Found == perf.Checked {
+			return perf
+		}
+
+		select {
+		case <-ctx.Done():
+			perf.Error = append(perf.Error, fmt.Sprintf("%d articles did not propagate: %s", perf.Checked-perf.Found, ctx.Err()))
+			return perf
+		case <-expire:
+			perf.Error = append(perf.Error, fmt.Sprintf("%d articles did not propagate within %s", perf.Checked-perf.Found, timeout))
+			return perf
+		case <-time.After(interval):
+		}
+	}
+}
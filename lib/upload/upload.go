@@ -0,0 +1,509 @@
+// Post a ZIP of UploadDir as yEnc parts and write the NZB
+// and manifest for download.
+package upload
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sla/lib/duration"
+	"sla/lib/manifest"
+	"sla/lib/nntp"
+	"sla/lib/nzb"
+	"sla/lib/stats"
+	"sla/lib/stream"
+	"sla/upload/yenc"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Address     string // server:port, used when Servers is empty
+	User        string
+	Pass        string
+	NzbDir      string
+	MsgDomain   string
+	UploadDir   string
+	Poster      string   // From-header and NZB poster
+	Groups      []string // Newsgroups to post in
+	TLS         nntp.TLS
+	Servers     []nntp.Server     // Posted through the first
+	Propagation PropagationConfig // Polling of the other servers
+
+	DialTimeout  duration.Duration
+	ReadTimeout  duration.Duration
+	WriteTimeout duration.Duration
+	Deadline     duration.Duration // Max duration of whole run
+	Conns        int               // Parallel connections, used when Servers is empty
+	Capabilities []string          // Expected, used when Servers is empty
+}
+
+// Servers of c, or the single server of older configs
+func Servers(c Config) []nntp.Server {
+	return nntp.Legacy(c.Servers, c.Address, c.User, c.Pass, c.TLS, c.Conns, c.Capabilities)
+}
+
+// Modes name the command used per article
+const MODE_POST = "post"     // Reader POST (default)
+const MODE_IHAVE = "ihave"   // Transit IHAVE
+const MODE_STREAM = "stream" // Streaming feed CHECK/TAKETHIS (RFC 4644)
+
+// Settings of one run
+type Options struct {
+	Mode    string // Command per article, default MODE_POST
+	Verbose bool
+}
+
+type ArtPerf struct {
+	MsgId    string
+	Conn     string  // Name of conn that posted
+	Time     float64 // duration in ms
+	TTFB     float64 // ms from POST until 340 (IHAVE 335, CHECK 238)
+	Transfer float64 // ms from 340 until terminator written
+	Ack      float64 // ms from terminator until 240 (IHAVE 235, TAKETHIS 239)
+	Size     int64
+	Speed    float64 // kb/sec
+	BitSpeed float64 // kbit/sec
+}
+
+type Perf struct {
+	Server      string            // Name of server posted through
+	Mode        string            // Command used per article
+	Conn        float64           // First connection
+	TLS         float64           // First connection
+	Auth        float64           // First connection
+	Caps        nntp.Capabilities // Of the first connection
+	Arts        []ArtPerf
+	Summary     Summary
+	TotalKBsec  float64 // All conns combined
+	Conns       []nntp.ConnPerf
+	Propagation []PropagationPerf // Other servers
+	Error       []string
+	Failures    []nntp.Failure // Error classified, same order
+}
+
+// Distribution of posted articles
+type Summary struct {
+	Time     stats.Summary // ms per article
+	TTFB     stats.Summary // ms from POST until 340
+	Transfer stats.Summary // ms from 340 until terminator written
+	Ack      stats.Summary // ms from terminator until 240 (server commit)
+	Speed    stats.Summary // kb/sec
+}
+
+func summarize(arts []ArtPerf) Summary {
+	var times, ttfb, transfer, ack, speed []float64
+	for _, art := range arts {
+		times = append(times, art.Time)
+		ttfb = append(ttfb, art.TTFB)
+		transfer = append(transfer, art.Transfer)
+		ack = append(ack, art.Ack)
+		speed = append(speed, art.Speed)
+	}
+	return Summary{
+		Time:     stats.Summarize(times),
+		TTFB:     stats.Summarize(ttfb),
+		Transfer: stats.Summarize(transfer),
+		Ack:      stats.Summarize(ack),
+		Speed:    stats.Summarize(speed),
+	}
+}
+
+func zipAdd(w *zip.Writer, name string, path string) error {
+	in, e := os.Open(path)
+	if e != nil {
+		return e
+	}
+	defer in.Close()
+
+	head := &zip.FileHeader{Name: name}
+	head.SetModTime(time.Now())
+	f, e := w.CreateHeader(head)
+	if e != nil {
+		return e
+	}
+	if _, e := io.Copy(f, bufio.NewReader(in)); e != nil {
+		return e
+	}
+	return nil
+}
+
+// Write path through a temporary file in the same dir and
+// rename it, so downloads never read a half written file.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, e := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-")
+	if e != nil {
+		return e
+	}
+	defer os.Remove(f.Name())
+	if e := write(f); e != nil {
+		f.Close()
+		return e
+	}
+	if e := f.Chmod(0644); e != nil {
+		f.Close()
+		return e
+	}
+	if e := f.Close(); e != nil {
+		return e
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Article headers, path is only set for IHAVE/TAKETHIS as
+// a reader server adds it on POST.
+func headers(subject string, msgid string, poster string, groups []string, path string) string {
+	headers := "Message-ID: <" + msgid + ">" + nntp.EOF
+	if path != "" {
+		headers += "Path: " + path + nntp.EOF
+	}
+	headers += "Date: " + time.Now().Format(time.RFC1123Z) + nntp.EOF
+	headers += "Organization: Usenet.Farm" + nntp.EOF
+	headers += "Subject: " + subject + nntp.EOF
+	headers += "From: " + poster + nntp.EOF
+	headers += "Newsgroups: " + strings.Join(groups, ",") + nntp.EOF
+	headers += nntp.EOF // End of header
+	return headers
+}
+
+func min(a int, b int64) int {
+	if a < int(b) {
+		return a
+	}
+	return int(b)
+}
+
+// Perf of a run that stopped with e
+func Failed(e error) Perf {
+	return Perf{
+		Arts:        []ArtPerf{},
+		Conns:       []nntp.ConnPerf{},
+		Propagation: []PropagationPerf{},
+		Error:       []string{e.Error()},
+		Failures:    []nntp.Failure{nntp.NewFailure(e)},
+	}
+}
+
+// Upload c.UploadDir and write YYYY-mm-dd.nzb/json to c.NzbDir
+func Run(ctx context.Context, c Config, o Options) (Perf, error) {
+	if !strings.HasSuffix(c.NzbDir, "/") {
+		c.NzbDir += "/"
+	}
+	if c.Poster == "" {
+		c.Poster = "Usenet.Farm <support@usenet.farm>"
+	}
+	if len(c.Groups) == 0 {
+		c.Groups = []string{"alt.binaries.test"}
+	}
+	if o.Mode == "" {
+		o.Mode = MODE_POST
+	}
+	if o.Mode != MODE_POST && o.Mode != MODE_IHAVE && o.Mode != MODE_STREAM {
+		return Perf{}, fmt.Errorf("Invalid mode: %s", o.Mode)
+	}
+	// Transit servers reject articles without Path
+	path := ""
+	if o.Mode != MODE_POST {
+		host, e := os.Hostname()
+		if e != nil {
+			return Perf{}, e
+		}
+		path = host + "!not-for-mail"
+	}
+
+	// Permission check nzbdir
+	{
+		stat, e := os.Stat(c.NzbDir)
+		if e != nil {
+			return Perf{}, e
+		}
+		if !stat.IsDir() {
+			return Perf{}, fmt.Errorf("Not a dir: %s", c.NzbDir)
+		}
+		if e := ioutil.WriteFile(
+			c.NzbDir+"check.txt",
+			[]byte("Write permission check."),
+			0400,
+		); e != nil {
+			return Perf{}, e
+		}
+		if e := os.Remove(c.NzbDir + "check.txt"); e != nil {
+			return Perf{}, e
+		}
+	}
+	// Permission check uploaddir
+	{
+		stat, e := os.Stat(c.UploadDir)
+		if e != nil {
+			return Perf{}, e
+		}
+		if !stat.IsDir() {
+			return Perf{}, fmt.Errorf("Not a dir: %s", c.UploadDir)
+		}
+	}
+
+	if o.Verbose {
+		fmt.Println("Building ZIP from dir=" + c.UploadDir)
+	}
+
+	filename := fmt.Sprintf("sla-%s.zip", time.Now().Format("2006-01-02"))
+	// ZIP on disk, only the parts in flight are kept in memory
+	tmp, e := ioutil.TempFile("", "sla-upload-")
+	if e != nil {
+		return Perf{}, e
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var enc *yenc.Writer
+	var partCount = 0
+	mf := manifest.Manifest{Name: filename}
+	{
+		h := sha256.New()
+		counter := stream.NewCountWriter(io.MultiWriter(tmp, h))
+		w := zip.NewWriter(counter)
+
+		e := filepath.Walk(c.UploadDir, func(path string, info os.FileInfo, err error) error {
+			if path == c.UploadDir {
+				// Ignore base
+				return nil
+			}
+			if strings.HasSuffix(info.Name(), ".sh") {
+				// Ignore scripts
+				if o.Verbose {
+					fmt.Println("Skip " + path)
+				}
+				return nil
+			}
+			if o.Verbose {
+				fmt.Println("Add " + path + " to ZIP.")
+			}
+			if e := zipAdd(w, info.Name(), path); e != nil {
+				return e
+			}
+			return nil
+		})
+		if e != nil {
+			return Perf{}, e
+		}
+
+		f, e := w.Create("unique.txt")
+		if e != nil {
+			return Perf{}, e
+		}
+		mf.Unique = RandStringRunes(16)
+		f.Write([]byte(mf.Unique))
+
+		if e := w.Close(); e != nil {
+			return Perf{}, e
+		}
+		mf.SHA256 = hex.EncodeToString(h.Sum(nil))
+		mf.Size = counter.Written()
+
+		if _, e := tmp.Seek(0, io.SeekStart); e != nil {
+			return Perf{}, e
+		}
+		enc = yenc.NewStreamWriter(bufio.NewReader(tmp), int(mf.Size), filename, yenc.PART_SIZE)
+		partCount = enc.Parts()
+	}
+	if partCount < 50 {
+		return Perf{}, fmt.Errorf("Need at least 50 parts, I got: %d (increase rand file?)", partCount)
+	}
+
+	subject := "Completion test " + time.Now().Format("2006-01-02")
+	if o.Verbose {
+		fmt.Println(fmt.Sprintf("Upload file=%s parts(%d)..", subject, partCount))
+	}
+
+	if c.Deadline.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Deadline.Duration)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	server := Servers(c)[0]
+	conns := server.Conns
+	if conns > partCount {
+		conns = partCount
+	}
+
+	if o.Verbose {
+		fmt.Printf("Connecting to %s with %d conns..\n", server.Name, conns)
+	}
+	posters := make([]*poster, conns)
+	for i := 0; i < conns; i++ {
+		p, e := newPoster(c, server, i+1, o.Mode, o.Verbose)
+		if e != nil {
+			return Perf{}, e
+		}
+		posters[i] = p
+	}
+
+	// Peers are polled while posting, each part from its own 240
+	peers := Servers(c)[1:]
+	if o.Verbose && len(peers) > 0 {
+		fmt.Printf("Polling %d servers for propagation..\n", len(peers))
+	}
+	prop := startPropagation(ctx, c, peers, partCount, o.Verbose)
+	for _, p := range posters {
+		p.prop = prop
+	}
+
+	// Only keep one encoded part per conn in memory, posters stop
+	// on the deferred cancel when encoding fails before close(jobs)
+	jobs := make(chan job, conns)
+	results := make(chan result, partCount)
+	errs := make(chan error, conns)
+	for _, p := range posters {
+		go func(p *poster) {
+			defer p.Close()
+			e := p.Connect(ctx, server.User, server.Pass)
+			if e == nil {
+				e = p.Run(ctx, jobs, results)
+			}
+			if e != nil {
+				// Stop others, first error is reported
+				cancel()
+			}
+			errs <- e
+		}(p)
+	}
+
+	msgids := make([]nzb.Msg, partCount)
+	begin := int64(1)
+	for idx := 0; enc.HasNext(); idx++ {
+		msgid := RandStringRunes(16) + c.MsgDomain
+		body := new(bytes.Buffer)
+
+		w := stream.NewCountWriter(body)
+		if _, e := w.WriteString(headers(subject, msgid, c.Poster, c.Groups, path)); e != nil {
+			return Perf{}, e
+		}
+		w.ResetWritten()
+
+		size, e := enc.EncodePart(w)
+		if e != nil {
+			return Perf{}, e
+		}
+		n := w.Written()
+		msgids[idx] = nzb.Msg{
+			Msgid: msgid,
+			Size:  n,
+		}
+		mf.Parts = append(mf.Parts, manifest.Part{
+			Number: idx + 1,
+			Msgid:  msgid,
+			Begin:  begin,
+			Size:   int64(size),
+			CRC32:  enc.PartCRC(),
+		})
+		begin += int64(size)
+
+		select {
+		case jobs <- job{Idx: idx, Msgid: msgid, Body: body, Size: n}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	for i := 0; i < conns; i++ {
+		if e := <-errs; e != nil {
+			return Perf{}, e
+		}
+	}
+	if e := ctx.Err(); e != nil {
+		return Perf{}, e
+	}
+	close(results)
+
+	artPerf := make([]ArtPerf, partCount)
+	var first, last time.Time
+	var total int64
+	for res := range results {
+		artPerf[res.Idx] = res.Perf
+		total += res.Perf.Size
+		if first.IsZero() || res.Begin.Before(first) {
+			first = res.Begin
+		}
+		if res.End.After(last) {
+			last = res.End
+		}
+	}
+	totalKBsec := float64(0)
+	if wall := last.Sub(first); wall > 0 {
+		totalKBsec = float64(total/1024) / wall.Seconds()
+	}
+
+	connPerf := []nntp.ConnPerf{}
+	for _, p := range posters {
+		connPerf = append(connPerf, p.Perf)
+	}
+
+	if err := enc.Close(); err != nil {
+		return Perf{}, err
+	}
+
+	xml, e := nzb.Marshal(&nzb.Nzb{
+		Meta: []nzb.Meta{nzb.Meta{Type: "title", Value: filename}},
+		Files: []nzb.File{nzb.File{
+			Poster:   c.Poster,
+			Date:     nzb.Date{Time: time.Now()},
+			Subject:  subject,
+			Groups:   c.Groups,
+			Segments: nzb.Segments(msgids),
+		}},
+	})
+	if e != nil {
+		return Perf{}, e
+	}
+	// Sidecar for download to verify the content, written before
+	// the NZB so a new NZB always has its manifest
+	day := c.NzbDir + time.Now().Format("2006-01-02")
+	if e := writeFile(day+".json", mf.Write); e != nil {
+		return Perf{}, e
+	}
+	if e := writeFile(day+".nzb", func(w io.Writer) error {
+		_, e := w.Write(xml)
+		return e
+	}); e != nil {
+		return Perf{}, e
+	}
+
+	perfErrs := []string{}
+	failures := []nntp.Failure{}
+	caps := posters[0].conn.Caps
+	if e := caps.Expect(server.Capabilities); e != nil {
+		perfErrs = append(perfErrs, e.Error())
+		failures = append(failures, nntp.NewFailure(e))
+	}
+
+	propPerf := prop.Wait()
+
+	return Perf{
+		Server:      server.Name,
+		Mode:        o.Mode,
+		Propagation: propPerf,
+		Conn:        connPerf[0].Conn,
+		TLS:         connPerf[0].TLS,
+		Auth:        connPerf[0].Auth,
+		Caps:        caps,
+		Arts:        artPerf,
+		Summary:     summarize(artPerf),
+		TotalKBsec:  totalKBsec,
+		Conns:       connPerf,
+		Error:       perfErrs,
+		Failures:    failures,
+	}, nil
+}
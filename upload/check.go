@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"sla/lib/nagios"
+	"sla/lib/upload"
+	"strings"
+)
+
+// Read the JSON written by upload
+func readResult(file string) (upload.Perf, error) {
+	var perf upload.Perf
+	b, e := ioutil.ReadFile(file)
+	if e != nil {
+		return perf, e
+	}
+	e = json.Unmarshal(b, &perf)
+	return perf, e
+}
+
+// Nagios plugin, returns the exit code
+func check(args []string) int {
+	var file, configPath string
+	var speed, avg, errs, propagation nagios.Threshold
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.StringVar(&file, "f", "", "/Path/to/result.json written by upload, empty runs the upload")
+	fs.StringVar(&configPath, "c", "./config.json", "/Path/to/config.json")
+	speed.Flags(fs, "speed", "KB/s", -1, 100)
+	avg.Flags(fs, "latency", "ms", -1, -1)
+	errs.Flags(fs, "errors", "count", -1, 0)
+	propagation.Flags(fs, "propagation", "ms", -1, -1)
+
+	// A bad flag is UNKNOWN, exit code 2 means CRITICAL to Nagios
+	c := nagios.New("SLA/UPLOAD")
+	if e := fs.Parse(args); e != nil {
+		c.Unknown(e.Error())
+		return c.Write(os.Stdout)
+	}
+	var perf upload.Perf
+	if file != "" {
+		var e error
+		if perf, e = readResult(file); e != nil {
+			c.Unknown(e.Error())
+			return c.Write(os.Stdout)
+		}
+	} else {
+		conf, e := loadConfig(configPath)
+		if e != nil {
+			c.Unknown(e.Error())
+			return c.Write(os.Stdout)
+		}
+		perf, e = upload.Run(context.Background(), conf, upload.Options{})
+		if e != nil {
+			perf = upload.Failed(e)
+		}
+	}
+
+	c.Min("speed", perf.TotalKBsec, "KB", speed)
+	c.Max("latency", perf.Summary.Time.Mean, "ms", avg)
+	c.Max("errors", float64(len(perf.Error)), "", errs)
+	if len(perf.Error) > 0 {
+		c.Info(strings.Join(perf.Error, ", "))
+	}
+	for _, p := range perf.Propagation {
+		// Slowest article that appeared, missing ones are in p.Error
+		c.Max(p.Name+" propagation", p.Max, "ms", propagation)
+		c.Max(p.Name+" errors", float64(len(p.Error)), "", errs)
+		if len(p.Error) > 0 {
+			c.Info(p.Name + " " + strings.Join(p.Error, ", "))
+		}
+	}
+	return c.Write(os.Stdout)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sla/lib/munin"
+	"sla/lib/upload"
+)
+
+func graphs(perf upload.Perf) []munin.Graph {
+	latency := munin.Graph{Name: "sla_upload_latency", Title: "SLA upload latency", VLabel: "ms", Category: "sla"}
+	speed := munin.Graph{Name: "sla_upload_speed", Title: "SLA upload speed", VLabel: "KB/s", Category: "sla"}
+	completion := munin.Graph{Name: "sla_upload_completion", Title: "SLA upload propagation", VLabel: "%", Category: "sla", Info: "Posted articles found on the other servers"}
+	errs := munin.Graph{Name: "sla_upload_errors", Title: "SLA upload errors", VLabel: "count", Category: "sla"}
+
+	latency.Add("conn", "Connect", perf.Conn)
+	latency.Add("auth", "Auth", perf.Auth)
+	latency.Add("p50", "Article p50", perf.Summary.Time.P50)
+	latency.Add("p90", "Article p90", perf.Summary.Time.P90)
+	latency.Add("p99", "Article p99", perf.Summary.Time.P99)
+	speed.Add("speed", "All conns", perf.TotalKBsec)
+	errs.Add("errors", "Errors", float64(len(perf.Error)))
+
+	graphs := []munin.Graph{latency, speed, errs}
+	if len(perf.Propagation) == 0 {
+		return graphs
+	}
+	for _, p := range perf.Propagation {
+		pct := float64(0)
+		if p.Checked > 0 {
+			pct = float64(p.Found) / float64(p.Checked) * 100
+		}
+		completion.Add(p.Name, p.Name, pct)
+	}
+	return append(graphs, completion)
+}
+
+// Munin plugin, `munin` prints values and `munin config` the graphs
+func muninPlugin(args []string) int {
+	var file string
+	def := os.Getenv("result")
+	if def == "" {
+		def = "/tmp/sla.upload.json.tmp"
+	}
+	fs := flag.NewFlagSet("munin", flag.ExitOnError)
+	fs.StringVar(&file, "f", def, "/Path/to/result.json written by upload (env.result)")
+	fs.Parse(args)
+
+	perf, e := readResult(file)
+	if fs.Arg(0) == "config" {
+		if e != nil {
+			// Graphs without propagation until the first result
+			perf = upload.Perf{}
+		}
+		e = munin.Config(os.Stdout, graphs(perf))
+	} else if e == nil {
+		e = munin.Values(os.Stdout, graphs(perf))
+	}
+	if e != nil {
+		fmt.Fprintln(os.Stderr, e)
+		return 1
+	}
+	return 0
+}
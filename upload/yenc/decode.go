@@ -0,0 +1,340 @@
+// Streaming yEnc decoder for (multipart) articles.
+package yenc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strconv"
+)
+
+// No =ybegin line found
+var ErrNoBegin = errors.New("yEnc =ybegin not found")
+
+// Data ended before =yend
+var ErrTruncated = errors.New("yEnc part truncated, =yend not found")
+
+// Decoded byte count differs from =yend/=ypart
+type SizeError struct {
+	Expect int64
+	Got    int64
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("yEnc size mismatch, expect=%d got=%d", e.Expect, e.Got)
+}
+
+// Checksum of decoded data differs from =yend
+type CRCError struct {
+	Expect uint32
+	Got    uint32
+}
+
+func (e *CRCError) Error() string {
+	return fmt.Sprintf("yEnc crc mismatch, expect=%08X got=%08X", e.Expect, e.Got)
+}
+
+// Malformed =ybegin, =ypart or =yend line
+type HeaderError struct {
+	Line  string // Offending line, empty for an invalid value
+	Key   string // Keyword with an invalid value
+	Value string
+	Err   error
+}
+
+func (e *HeaderError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("yEnc invalid %s=%s: %s", e.Key, e.Value, e.Err)
+	}
+	return fmt.Sprintf("yEnc %s, got: %q", e.Err, e.Line)
+}
+
+func (e *HeaderError) Unwrap() error {
+	return e.Err
+}
+
+var errNoPart = errors.New("=ypart expected")
+var errKeyword = errors.New("invalid keyword")
+
+// Keywords of =ybegin and =ypart
+type Header struct {
+	Part  int
+	Total int
+	Line  int
+	Size  int64 // Size of whole file
+	Name  string
+	Begin int64 // First byte of part (1-based)
+	End   int64 // Last byte of part
+}
+
+// Keywords of =yend
+type Footer struct {
+	Size    int64
+	Part    int
+	PCRC32  uint32 // Checksum of part
+	CRC32   uint32 // Checksum of whole file
+	HasPCRC bool
+	HasCRC  bool
+}
+
+// Decoder reads decoded data of one yEnc part from r,
+// Read returns io.EOF once =yend is found and verified.
+type Decoder struct {
+	Header Header
+	Footer Footer
+
+	r       *bufio.Reader
+	begun   bool
+	escaped bool // Last line ended with =
+	bol     bool // At begin of line
+	crc     hash.Hash32
+	n       int64  // Decoded bytes
+	out     []byte // Decoded line
+	pending []byte // Not yet read from out
+	err     error
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r:   bufio.NewReader(r),
+		crc: crc32.NewIEEE(),
+		bol: true,
+	}
+}
+
+// Decode r and write the part to w
+func Decode(w io.Writer, r io.Reader) (*Decoder, error) {
+	d := NewDecoder(r)
+	_, e := io.Copy(w, d)
+	return d, e
+}
+
+func (d *Decoder) Read(p []byte) (int, error) {
+	if !d.begun {
+		d.begun = true
+		d.err = d.readHeader()
+	}
+	for len(d.pending) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.decodeLine()
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// Bytes decoded so far
+func (d *Decoder) Count() int64 {
+	return d.n
+}
+
+// Checksum of bytes decoded so far
+func (d *Decoder) CRC32() uint32 {
+	return d.crc.Sum32()
+}
+
+func (d *Decoder) readLine() ([]byte, error) {
+	line, e := d.r.ReadSlice('\n')
+	if e == io.EOF && len(line) > 0 {
+		// Last line without CRLF
+		e = nil
+	}
+	return line, e
+}
+
+// Skip everything until =ybegin and parse it (and =ypart)
+func (d *Decoder) readHeader() error {
+	for {
+		line, e := d.r.ReadBytes('\n')
+		if e == io.EOF {
+			return ErrNoBegin
+		}
+		if e != nil {
+			return e
+		}
+		if bytes.HasPrefix(line, []byte("=ybegin ")) {
+			if e := parseHeader(&d.Header, line); e != nil {
+				return e
+			}
+			break
+		}
+	}
+	if d.Header.Part == 0 {
+		// Single part
+		return nil
+	}
+
+	line, e := d.r.ReadBytes('\n')
+	if e == io.EOF {
+		return ErrTruncated
+	}
+	if e != nil {
+		return e
+	}
+	if !bytes.HasPrefix(line, []byte("=ypart ")) {
+		return &HeaderError{Line: string(bytes.TrimRight(line, "\r\n")), Err: errNoPart}
+	}
+	return parseHeader(&d.Header, line)
+}
+
+func (d *Decoder) decodeLine() error {
+	line, e := d.readLine()
+	if e == io.EOF || e == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	if e != nil && e != bufio.ErrBufferFull {
+		return e
+	}
+	bol := d.bol
+	d.bol = e == nil
+
+	if bol && bytes.HasPrefix(line, []byte("=yend")) {
+		if e := parseFooter(&d.Footer, line); e != nil {
+			return e
+		}
+		if e := d.verify(); e != nil {
+			return e
+		}
+		return io.EOF
+	}
+
+	d.out = d.out[:0]
+	for _, b := range line {
+		if d.escaped {
+			d.escaped = false
+			d.out = append(d.out, b-64-42)
+			continue
+		}
+		switch b {
+		case '\r', '\n':
+			// Line endings are not data
+		case '=':
+			d.escaped = true
+		default:
+			d.out = append(d.out, b-42)
+		}
+	}
+	d.crc.Write(d.out)
+	d.n += int64(len(d.out))
+	d.pending = d.out
+	return nil
+}
+
+func (d *Decoder) verify() error {
+	h := d.Header
+	f := d.Footer
+
+	// Older uploads used a 0-based begin and swapped the
+	// sizes of =ybegin and =yend (file size < part size)
+	legacy := h.Part > 0 && (h.Begin == 0 || h.Size < f.Size)
+	if h.Part > 0 {
+		expect := h.End - h.Begin + 1
+		if legacy {
+			expect = h.End - h.Begin
+		}
+		if expect != d.n {
+			return &SizeError{Expect: expect, Got: d.n}
+		}
+	}
+	expect := f.Size
+	if legacy {
+		expect = h.Size
+	}
+	if expect != d.n {
+		return &SizeError{Expect: expect, Got: d.n}
+	}
+
+	if f.HasPCRC {
+		if f.PCRC32 != d.crc.Sum32() {
+			return &CRCError{Expect: f.PCRC32, Got: d.crc.Sum32()}
+		}
+	} else if f.HasCRC && h.Part == 0 {
+		// Single part, so file checksum is part checksum
+		if f.CRC32 != d.crc.Sum32() {
+			return &CRCError{Expect: f.CRC32, Got: d.crc.Sum32()}
+		}
+	}
+	return nil
+}
+
+// Walk key=value pairs, name is always last and may contain spaces.
+func keywords(line []byte, fn func(key string, value string) error) error {
+	line = bytes.TrimRight(line, "\r\n")
+	// Strip =ybegin/=ypart/=yend
+	if idx := bytes.IndexByte(line, ' '); idx != -1 {
+		line = line[idx+1:]
+	} else {
+		return nil
+	}
+	for len(line) > 0 {
+		line = bytes.TrimLeft(line, " ")
+		eq := bytes.IndexByte(line, '=')
+		if eq == -1 {
+			return &HeaderError{Line: string(line), Err: errKeyword}
+		}
+		key := string(line[:eq])
+		line = line[eq+1:]
+
+		var value []byte
+		if key == "name" {
+			value, line = line, nil
+		} else if sp := bytes.IndexByte(line, ' '); sp != -1 {
+			value, line = line[:sp], line[sp+1:]
+		} else {
+			value, line = line, nil
+		}
+		if e := fn(key, string(value)); e != nil {
+			return &HeaderError{Key: key, Value: string(value), Err: e}
+		}
+	}
+	return nil
+}
+
+func parseHeader(h *Header, line []byte) error {
+	return keywords(line, func(key string, value string) (e error) {
+		switch key {
+		case "part":
+			h.Part, e = strconv.Atoi(value)
+		case "total":
+			h.Total, e = strconv.Atoi(value)
+		case "line":
+			h.Line, e = strconv.Atoi(value)
+		case "size":
+			h.Size, e = strconv.ParseInt(value, 10, 64)
+		case "name":
+			h.Name = value
+		case "begin":
+			h.Begin, e = strconv.ParseInt(value, 10, 64)
+		case "end":
+			h.End, e = strconv.ParseInt(value, 10, 64)
+		}
+		return
+	})
+}
+
+func parseFooter(f *Footer, line []byte) error {
+	return keywords(line, func(key string, value string) (e error) {
+		var crc uint64
+		switch key {
+		case "size":
+			f.Size, e = strconv.ParseInt(value, 10, 64)
+		case "part":
+			f.Part, e = strconv.Atoi(value)
+		case "pcrc32":
+			crc, e = strconv.ParseUint(value, 16, 32)
+			f.PCRC32 = uint32(crc)
+			f.HasPCRC = true
+		case "crc32":
+			crc, e = strconv.ParseUint(value, 16, 32)
+			f.CRC32 = uint32(crc)
+			f.HasCRC = true
+		}
+		return
+	})
+}
@@ -0,0 +1,185 @@
+package yenc
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDecodeText(t *testing.T) {
+	expect, err := ioutil.ReadFile("../test/test1.in")
+	if err != nil {
+		t.Fatalf("couldn't open test1.in: %s", err)
+	}
+	in, err := ioutil.ReadFile("../test/test1.ync")
+	if err != nil {
+		t.Fatalf("couldn't open test1.ync: %s", err)
+	}
+
+	out := new(bytes.Buffer)
+	d, err := Decode(out, bytes.NewReader(in))
+	if err != nil {
+		t.Fatalf("decode failed %s", err)
+	}
+	if bytes.Compare(expect, out.Bytes()) != 0 {
+		t.Fatalf("data mismatch")
+	}
+	if d.Header.Name != "test1.in" || d.Header.Size != 858 || d.Footer.PCRC32 != 0x3274F3F7 {
+		t.Fatalf("header mismatch %+v %+v", d.Header, d.Footer)
+	}
+}
+
+func TestDecodeBinary(t *testing.T) {
+	expect, err := ioutil.ReadFile("../test/test2.in")
+	if err != nil {
+		t.Fatalf("couldn't open test2.in: %s", err)
+	}
+	in, err := ioutil.ReadFile("../test/test2.ync")
+	if err != nil {
+		t.Fatalf("couldn't open test2.ync: %s", err)
+	}
+
+	out := new(bytes.Buffer)
+	if _, err := Decode(out, bytes.NewReader(in)); err != nil {
+		t.Fatalf("decode failed %s", err)
+	}
+	if bytes.Compare(expect, out.Bytes()) != 0 {
+		t.Fatalf("data mismatch")
+	}
+}
+
+func TestDecodeMultipart(t *testing.T) {
+	in := makeInBuf(10)
+	enc := NewWriter(new(bytes.Buffer), "test.bin", 100000)
+	if _, err := enc.Write(in); err != nil {
+		t.Fatal(err)
+	}
+	if enc.Parts() != 4 {
+		t.Fatalf("expect 4 parts, got %d", enc.Parts())
+	}
+
+	out := new(bytes.Buffer)
+	for part := 1; enc.HasNext(); part++ {
+		art := new(bytes.Buffer)
+		// Decoder must skip article headers
+		io.WriteString(art, "Subject: test\r\n\r\n")
+		if _, err := enc.EncodePart(art); err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := Decode(out, art)
+		if err != nil {
+			t.Fatalf("part %d: %s", part, err)
+		}
+		if d.Header.Part != part || d.Header.Size != int64(len(in)) {
+			t.Fatalf("part %d: header mismatch %+v", part, d.Header)
+		}
+		if int64(out.Len()) != d.Header.End {
+			t.Fatalf("part %d: end=%d but decoded=%d", part, d.Header.End, out.Len())
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(in, out.Bytes()) != 0 {
+		t.Fatalf("data mismatch")
+	}
+}
+
+func TestStreamWriter(t *testing.T) {
+	in := makeInBuf(10)
+	enc := NewStreamWriter(bytes.NewReader(in), len(in), "test.bin", 100000)
+	if enc.Parts() != 4 {
+		t.Fatalf("expect 4 parts, got %d", enc.Parts())
+	}
+	if _, err := enc.Write(in); err == nil {
+		t.Fatal("Write to stream should fail")
+	}
+
+	out := new(bytes.Buffer)
+	for enc.HasNext() {
+		art := new(bytes.Buffer)
+		if _, err := enc.EncodePart(art); err != nil {
+			t.Fatal(err)
+		}
+		d, err := Decode(out, art)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.CRC32() != enc.PartCRC() {
+			t.Fatalf("crc expect=%08X got=%08X", enc.PartCRC(), d.CRC32())
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(in, out.Bytes()) != 0 {
+		t.Fatalf("data mismatch")
+	}
+}
+
+func TestDecodeLegacy(t *testing.T) {
+	// Sizes as written by older upload versions
+	in := "=ybegin part=2 total=2 line=128 size=3 name=test.bin\r\n" +
+		"=ypart begin=10 end=13\r\n" +
+		"\x8b\x8c\x8d\r\n" +
+		"=yend size=13 part=2 pcrc32=352441C2\r\n"
+	out := new(bytes.Buffer)
+	if _, err := Decode(out, strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "abc" {
+		t.Fatalf("data mismatch %q", out.String())
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := map[string]string{
+		"crc": "=ybegin part=1 total=1 line=128 size=3 name=test.bin\r\n" +
+			"=ypart begin=1 end=3\r\n" +
+			"\x8b\x8c\x8d\r\n" +
+			"=yend size=3 part=1 pcrc32=00000000\r\n",
+		"size": "=ybegin line=128 size=4 name=test.bin\r\n" +
+			"\x8b\x8c\x8d\r\n" +
+			"=yend size=4 crc32=352441C2\r\n",
+		"truncated": "=ybegin part=1 total=1 line=128 size=3 name=test.bin\r\n" +
+			"=ypart begin=1 end=3\r\n" +
+			"\x8b\x8c\x8d\r\n",
+		"nobegin": "Subject: test\r\n\r\n",
+		"header": "=ybegin part=x total=1 line=128 size=3 name=test.bin\r\n" +
+			"=ypart begin=1 end=3\r\n" +
+			"\x8b\x8c\x8d\r\n" +
+			"=yend size=3 part=1\r\n",
+		"ypart": "=ybegin part=1 total=1 line=128 size=3 name=test.bin\r\n" +
+			"\x8b\x8c\x8d\r\n" +
+			"=yend size=3 part=1\r\n",
+	}
+
+	for name, in := range tests {
+		_, err := Decode(ioutil.Discard, strings.NewReader(in))
+		switch name {
+		case "crc":
+			if _, ok := err.(*CRCError); !ok {
+				t.Errorf("%s: expect CRCError, got %v", name, err)
+			}
+		case "size":
+			if _, ok := err.(*SizeError); !ok {
+				t.Errorf("%s: expect SizeError, got %v", name, err)
+			}
+		case "truncated":
+			if err != ErrTruncated {
+				t.Errorf("%s: expect ErrTruncated, got %v", name, err)
+			}
+		case "nobegin":
+			if err != ErrNoBegin {
+				t.Errorf("%s: expect ErrNoBegin, got %v", name, err)
+			}
+		case "header", "ypart":
+			if _, ok := err.(*HeaderError); !ok {
+				t.Errorf("%s: expect HeaderError, got %v", name, err)
+			}
+		}
+	}
+}
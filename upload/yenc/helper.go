@@ -33,3 +33,10 @@ func yencEnd(size, part int, hash uint32) string {
 	}
 	return fmt.Sprintf("=yend size=%d part=%d pcrc32=%08X\r\n", size, part, hash)
 }
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -12,7 +12,9 @@ const PART_SIZE = 768000 // Default size (750kb)
 
 type Writer struct {
 	partSize int      // Size per part
-	buf *bytes.Buffer // Queue
+	buf *bytes.Buffer // Queue, nil when streaming from r
+	r io.Reader       // Source of parts
+	in []byte         // Current part
 	filename string   // Original filename
 
 	bytePos int       // Current byte position
@@ -20,12 +22,25 @@ type Writer struct {
 
 	byteCount int     // Current size
 	posCount int      //
+	crc uint32        // Checksum of last part
 }
 
 // NewWriter returns a new Writer to create multipart yEnc
 func NewWriter(buf *bytes.Buffer, filename string, partSize int) *Writer {
 	return &Writer{
 		buf: buf,
+		r: buf,
+		filename: filename,
+		partSize: partSize,
+	}
+}
+
+// NewStreamWriter returns a Writer that encodes size bytes read
+// from r, keeping only one part in memory.
+func NewStreamWriter(r io.Reader, size int, filename string, partSize int) *Writer {
+	return &Writer{
+		r: r,
+		byteCount: size,
 		filename: filename,
 		partSize: partSize,
 	}
@@ -36,6 +51,9 @@ func (w *Writer) Write(p []byte) (int, error) {
 	if w.pos != 0 {
 		return 0, fmt.Errorf("cannot write once reading started")
 	}
+	if w.buf == nil {
+		return 0, fmt.Errorf("cannot write to stream")
+	}
 	
 	n, e := w.buf.Write(p)
 	w.byteCount += n
@@ -76,8 +94,13 @@ func (w *Writer) EncodePart(out io.Writer) (int, error) {
 		pos = w.pos
 	}
 
-	//buf := new(bytes.Buffer)
-	bufIn := w.buf.Next(w.partSize)
+	if w.in == nil {
+		w.in = make([]byte, w.partSize)
+	}
+	bufIn := w.in[:min(w.partSize, w.byteCount-w.bytePos)]
+	if _, err := io.ReadFull(w.r, bufIn); err != nil {
+		return 0, err
+	}
 	size := len(bufIn)
 	begin := w.bytePos
 	w.bytePos = w.bytePos + size
@@ -85,8 +108,8 @@ func (w *Writer) EncodePart(out io.Writer) (int, error) {
 
 	// header
 	{
-		prefix := yencHeader(pos, w.posCount, size, w.filename)
-		prefix = prefix + yencPart(begin, end)
+		prefix := yencHeader(pos, w.posCount, w.byteCount, w.filename)
+		prefix = prefix + yencPart(begin+1, end)
 		if _, err := out.Write([]byte(prefix)); err != nil {
 			return 0, err
 		}
@@ -102,7 +125,8 @@ func (w *Writer) EncodePart(out io.Writer) (int, error) {
 	{
 		h := crc32.NewIEEE()
 		h.Write(bufIn)
-		suffix := yencEnd(w.byteCount, pos, h.Sum32())
+		w.crc = h.Sum32()
+		suffix := yencEnd(size, pos, w.crc)
 		if _, err := out.Write([]byte(suffix)); err != nil {
 			return 0, err
 		}
@@ -110,6 +134,11 @@ func (w *Writer) EncodePart(out io.Writer) (int, error) {
 	return size, nil
 }
 
+// PartCRC returns the checksum of the last encoded part
+func (w *Writer) PartCRC() uint32 {
+	return w.crc
+}
+
 func (w *Writer) HasNext() bool {
     if w.pos == w.posCount {
     	return false
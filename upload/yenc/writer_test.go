@@ -0,0 +1,68 @@
+package yenc
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+// First two and last line of every part
+func TestWriterHeaders(t *testing.T) {
+	in := makeInBuf(1)[:250]
+	enc := NewWriter(new(bytes.Buffer), "test.bin", 100)
+	if _, err := enc.Write(in); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := [][3]string{
+		{"=ybegin part=1 total=3 line=128 size=250 name=test.bin", "=ypart begin=1 end=100", "=yend size=100 part=1 pcrc32=%08X"},
+		{"=ybegin part=2 total=3 line=128 size=250 name=test.bin", "=ypart begin=101 end=200", "=yend size=100 part=2 pcrc32=%08X"},
+		{"=ybegin part=3 total=3 line=128 size=250 name=test.bin", "=ypart begin=201 end=250", "=yend size=50 part=3 pcrc32=%08X"},
+	}
+	for i, lines := range expect {
+		art := new(bytes.Buffer)
+		if _, err := enc.EncodePart(art); err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Split(strings.TrimSuffix(art.String(), "\r\n"), "\r\n")
+		end := (i + 1) * 100
+		if end > len(in) {
+			end = len(in)
+		}
+		crc := crc32.ChecksumIEEE(in[i*100 : end])
+		if got[0] != lines[0] || got[1] != lines[1] || got[len(got)-1] != fmt.Sprintf(lines[2], crc) {
+			t.Errorf("part %d: expect %q, %q, %q\ngot %q, %q, %q", i+1,
+				lines[0], lines[1], fmt.Sprintf(lines[2], crc),
+				got[0], got[1], got[len(got)-1])
+		}
+	}
+}
+
+// Articles of older uploads, =ybegin with the part size, a
+// 0-based =ypart begin and =yend with the file size.
+func TestDecodeLegacyWriter(t *testing.T) {
+	in := makeInBuf(1)[:250]
+	out := new(bytes.Buffer)
+	for part, begin := 1, 0; begin < len(in); part, begin = part+1, begin+100 {
+		end := begin + 100
+		if end > len(in) {
+			end = len(in)
+		}
+		art := new(bytes.Buffer)
+		art.WriteString(yencHeader(part, 3, end-begin, "test.bin"))
+		art.WriteString(yencPart(begin, end))
+		if err := newEncoder(art, LINE_LENGTH).Encode(in[begin:end]); err != nil {
+			t.Fatal(err)
+		}
+		art.WriteString(yencEnd(len(in), part, crc32.ChecksumIEEE(in[begin:end])))
+
+		if _, err := Decode(out, art); err != nil {
+			t.Fatalf("part %d: %s", part, err)
+		}
+	}
+	if bytes.Compare(in, out.Bytes()) != 0 {
+		t.Fatalf("data mismatch")
+	}
+}